@@ -0,0 +1,84 @@
+package analysis
+
+import "sync"
+
+// --------------------------
+// Session events
+// --------------------------
+
+type EventType int
+
+const (
+	EventPosition EventType = iota // the shown position changed
+	EventEval                      // the engine finished an iteration
+)
+
+type Event struct {
+	Type EventType
+}
+
+// --------------------------
+// Subscribers
+// --------------------------
+
+// eventBufferSize is how many events a slow subscriber can lag behind
+// before further events are dropped for it
+const eventBufferSize = 16
+
+type eventHub struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	closed bool
+}
+
+// Subscribe returns a channel receiving the session's events and a
+// function that cancels the subscription. Events are dropped, never
+// blocked on, when the subscriber falls behind. The channel is closed when
+// the session is.
+func (s *Session) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+
+	s.events.mu.Lock()
+	if s.events.closed {
+		s.events.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if s.events.subs == nil {
+		s.events.subs = make(map[chan Event]struct{})
+	}
+	s.events.subs[ch] = struct{}{}
+	s.events.mu.Unlock()
+
+	cancel := func() {
+		s.events.mu.Lock()
+		defer s.events.mu.Unlock()
+		if _, ok := s.events.subs[ch]; ok {
+			delete(s.events.subs, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+func (h *eventHub) publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// close ends every subscription, and any made later
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
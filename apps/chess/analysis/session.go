@@ -0,0 +1,397 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+	"golang.org/x/sync/semaphore"
+)
+
+// --------------------------
+// Engine settings
+// --------------------------
+
+// ttMB sizes each session's transposition table in megabytes
+const ttMB = 32
+
+// maxDepth and maxTime bound one analysis run, so an idle board does not
+// keep a core busy forever
+const (
+	maxDepth = 64
+	maxTime  = 30 * time.Second
+)
+
+// Threads is the number of search threads per analysis (Lazy SMP). Every
+// open board may be searching at once, so by default each takes one core.
+var Threads = 1
+
+// MaxThreads bounds the search threads of all sessions together. Searches
+// beyond it wait for a running one to finish or be cancelled.
+var MaxThreads = runtime.NumCPU()
+
+var (
+	threadsOnce sync.Once
+	threadPool  *semaphore.Weighted
+)
+
+// searchThreads is the pool searches draw their threads from, sized by
+// MaxThreads when first used
+func searchThreads() *semaphore.Weighted {
+	threadsOnce.Do(func() {
+		threadPool = semaphore.NewWeighted(int64(max(MaxThreads, 1)))
+	})
+	return threadPool
+}
+
+// TB, if set, gives exact results once few enough pieces remain
+var TB *engine.Tablebase
+
+var ErrNoMove = errors.New("no move to step to")
+
+// --------------------------
+// Session
+// --------------------------
+
+// Eval is the engine's latest verdict on the shown position. Scores are
+// from white's point of view.
+type Eval struct {
+	Depth  int
+	Score  int      // centipawns, ±MATE_SCORE once mated
+	Mate   int      // moves to mate, negative when black mates, 0 if none
+	Line   []string // best line in SAN
+	Result string   // set instead of a search for finished positions
+}
+
+// Selection is the piece picked up on the board and where it can go
+type Selection struct {
+	From    uint8
+	Targets []uint8
+}
+
+// Session is one analysis board: a root position, the moves played from it
+// and a cursor into them. Every change of the shown position cancels the
+// running search and starts a new one.
+type Session struct {
+	ID string
+
+	mu        sync.Mutex
+	root      *engine.Board
+	moves     []engine.Move
+	cursor    int // moves[:cursor] lead to the shown position
+	selection *Selection
+	eval      Eval
+
+	tt     *engine.TranspositionTable // allocated with the first search
+	cancel context.CancelFunc
+	gen    uint64 // bumped per search; infos from older searches are dropped
+	closed bool
+
+	lastActive time.Time
+
+	events eventHub
+}
+
+// NewSession sets up a board on fen (the initial position when empty).
+// Analysis begins with Start.
+func NewSession(fen string) (*Session, error) {
+	root, err := parseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		ID:         uuid.New().String(),
+		root:       root,
+		lastActive: time.Now(),
+	}, nil
+}
+
+// Start analyses the initial position
+func (s *Session) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.analyse()
+}
+
+func parseFEN(fen string) (*engine.Board, error) {
+	fen = strings.TrimSpace(fen)
+	if fen == "" {
+		return engine.NewBoard(), nil
+	}
+	return engine.ParseFEN(fen)
+}
+
+// SetFEN replaces the position and drops the moves played so far
+func (s *Session) SetFEN(fen string) error {
+	root, err := parseFEN(fen)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = time.Now()
+	s.root = root
+	s.moves = nil
+	s.cursor = 0
+	s.changed()
+	return nil
+}
+
+// Close stops the running search for good, frees the transposition table
+// and ends every event subscription
+func (s *Session) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.tt = nil
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	s.events.close()
+}
+
+// LastActive is when the board was last shown or changed
+func (s *Session) LastActive() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastActive
+}
+
+// --------------------------
+// Moves and navigation
+// --------------------------
+
+// position returns the shown position
+func (s *Session) position() *engine.Board {
+	b := s.root.Clone()
+	for _, m := range s.moves[:s.cursor] {
+		b.MakeMove(m)
+	}
+	return b
+}
+
+// SelectSquare picks up a piece of the side to move, or drops the
+// selection when square holds none
+func (s *Session) SelectSquare(square uint8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = time.Now()
+
+	b := s.position()
+	color, _, ok := b.PieceAt(square)
+	if !ok || color != b.SideToMove {
+		s.selection = nil
+		return
+	}
+
+	moves := b.GenerateMovesForSquare(square)
+	targets := make([]uint8, 0, len(moves))
+	for _, m := range moves {
+		targets = append(targets, b.MoveTarget(m))
+	}
+	s.selection = &Selection{From: square, Targets: targets}
+}
+
+// MoveTo plays the selected piece to square, promoting to promo if it is
+// a pawn reaching the last rank. It returns false if that is not a legal
+// move. Moves after the cursor are kept when the move follows them and
+// dropped otherwise.
+func (s *Session) MoveTo(square uint8, promo engine.Piece) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = time.Now()
+
+	if s.selection == nil {
+		return false
+	}
+
+	b := s.position()
+	for _, m := range b.GenerateMovesForSquare(s.selection.From) {
+		if b.MoveTarget(m) != square || (m.Promotion != engine.NoPiece && m.Promotion != promo) {
+			continue
+		}
+
+		if s.cursor < len(s.moves) && s.moves[s.cursor] == m {
+			s.cursor++
+		} else {
+			s.moves = append(s.moves[:s.cursor], m)
+			s.cursor++
+		}
+		s.changed()
+		return true
+	}
+	return false
+}
+
+// Back steps one move towards the root position
+func (s *Session) Back() error {
+	return s.Seek(func(cursor, _ int) int { return cursor - 1 })
+}
+
+// Forward steps one move along the played line
+func (s *Session) Forward() error {
+	return s.Seek(func(cursor, _ int) int { return cursor + 1 })
+}
+
+// Seek moves the cursor to where(cursor, number of moves)
+func (s *Session) Seek(where func(cursor, n int) int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = time.Now()
+
+	to := where(s.cursor, len(s.moves))
+	if to < 0 || to > len(s.moves) || to == s.cursor {
+		return ErrNoMove
+	}
+	s.cursor = to
+	s.changed()
+	return nil
+}
+
+// changed restarts analysis after the shown position changed. Callers hold
+// s.mu.
+func (s *Session) changed() {
+	s.selection = nil
+	s.analyse()
+	s.events.publish(Event{Type: EventPosition})
+}
+
+// --------------------------
+// Engine
+// --------------------------
+
+// analyse cancels the running search and starts one on the shown position
+// once enough threads are free. Callers hold s.mu.
+func (s *Session) analyse() {
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	if s.closed {
+		return
+	}
+	s.gen++
+	s.eval = Eval{}
+
+	board := s.position()
+	if len(board.LegalMoves()) == 0 {
+		s.eval.Result = "Stalemate"
+		if board.IsKingInCheck(board.SideToMove) {
+			s.eval.Result = "Checkmate"
+			s.eval.Score = engine.MATE_SCORE
+			if board.SideToMove == engine.White {
+				s.eval.Score = -engine.MATE_SCORE
+			}
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	if s.tt == nil {
+		s.tt = engine.NewTT(ttMB)
+	}
+
+	gen := s.gen
+	pos := board.Clone() // the search works on board; SAN needs a stable copy
+	threads := min(max(Threads, 1), max(MaxThreads, 1))
+	searcher := &engine.Searcher{
+		TT:      s.tt,
+		Threads: threads,
+		TB:      TB,
+		OnInfo: func(info engine.SearchInfo) {
+			s.report(gen, pos, info)
+		},
+	}
+	go func() {
+		// Fails only once the position has moved on, or the session closed
+		if err := searchThreads().Acquire(ctx, int64(threads)); err != nil {
+			return
+		}
+		defer searchThreads().Release(int64(threads))
+		searcher.SearchContext(ctx, board, maxDepth, maxTime)
+	}()
+}
+
+// report records an iteration's result unless the position has moved on
+func (s *Session) report(gen uint64, pos *engine.Board, info engine.SearchInfo) {
+	eval := Eval{
+		Depth: info.Depth,
+		Score: info.Score,
+		Mate:  info.Mate,
+		Line:  sanLine(pos, info.PV),
+	}
+	if pos.SideToMove == engine.Black {
+		eval.Score, eval.Mate = -eval.Score, -eval.Mate
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if gen != s.gen {
+		return
+	}
+	s.eval = eval
+	s.events.publish(Event{Type: EventEval})
+}
+
+// sanLine renders a line of moves from pos in SAN
+func sanLine(pos *engine.Board, pv []engine.Move) []string {
+	replay := pos.Clone()
+	sans := make([]string, 0, len(pv))
+	for _, m := range pv {
+		sans = append(sans, replay.SAN(m))
+		replay.MakeMove(m)
+	}
+	return sans
+}
+
+// --------------------------
+// Snapshot
+// --------------------------
+
+// Snapshot is a consistent copy of the session for rendering
+type Snapshot struct {
+	ID        string
+	Board     *engine.Board // the shown position
+	FEN       string
+	Moves     []string // all played moves in SAN
+	Cursor    int
+	Selection *Selection
+	Eval      Eval
+}
+
+// Snapshot copies the session for rendering. Showing the board counts as
+// using it.
+func (s *Session) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = time.Now()
+
+	line := s.root.Clone()
+	for _, m := range s.moves {
+		line.MakeMove(m)
+	}
+
+	board := s.position()
+	snap := Snapshot{
+		ID:     s.ID,
+		Board:  board,
+		FEN:    board.FEN(),
+		Moves:  line.SANHistory(),
+		Cursor: s.cursor,
+		Eval:   s.eval,
+	}
+	if s.selection != nil {
+		sel := *s.selection
+		snap.Selection = &sel
+	}
+	return snap
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+)
+
+// --------------------------
+// EPD test suites
+// --------------------------
+
+// testPosition is one EPD record: the position, the moves that solve it
+// (bm) and the moves that fail it (am). Either list may be empty.
+type testPosition struct {
+	ID    string
+	Board *engine.Board
+	Best  []engine.Move
+	Avoid []engine.Move
+}
+
+// readEPD parses an EPD suite, one record per line:
+//
+//	<board> <side> <castling> <ep> bm Qg6; id "WAC.001";
+//
+// bm and am take one or more SAN moves; a record needs at least one of
+// them. Blank lines and lines starting with # are skipped.
+func readEPD(path string) ([]testPosition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var suite []testPosition
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pos, err := parseEPD(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if pos.ID == "" {
+			pos.ID = fmt.Sprintf("%s:%d", path, n)
+		}
+		suite = append(suite, pos)
+	}
+	return suite, sc.Err()
+}
+
+func parseEPD(line string) (testPosition, error) {
+	fields := strings.SplitN(line, " ", 5)
+	if len(fields) < 5 {
+		return testPosition{}, fmt.Errorf("expected a position and operations")
+	}
+	b, err := engine.ParseFEN(strings.Join(fields[:4], " "))
+	if err != nil {
+		return testPosition{}, err
+	}
+
+	pos := testPosition{Board: b}
+	for _, op := range strings.Split(fields[4], ";") {
+		opcode, operands, _ := strings.Cut(strings.TrimSpace(op), " ")
+		switch opcode {
+		case "bm", "am":
+			moves, err := parseSANList(b, operands)
+			if err != nil {
+				return testPosition{}, fmt.Errorf("%s: %w", opcode, err)
+			}
+			if opcode == "bm" {
+				pos.Best = moves
+			} else {
+				pos.Avoid = moves
+			}
+		case "id":
+			pos.ID = strings.Trim(strings.TrimSpace(operands), `"`)
+		}
+	}
+	if len(pos.Best) == 0 && len(pos.Avoid) == 0 {
+		return testPosition{}, fmt.Errorf("no bm or am operation")
+	}
+	return pos, nil
+}
+
+func parseSANList(b *engine.Board, s string) ([]engine.Move, error) {
+	var moves []engine.Move
+	for _, san := range strings.Fields(s) {
+		m, err := b.ParseSAN(san)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, m)
+	}
+	return moves, nil
+}
+
+// solved reports whether m is one of the best moves, if any are given, and
+// none of the moves to avoid
+func (p testPosition) solved(m engine.Move) bool {
+	same := func(o engine.Move) bool {
+		return o.From == m.From && o.To == m.To && o.Promotion == m.Promotion
+	}
+	if len(p.Best) > 0 && !slices.ContainsFunc(p.Best, same) {
+		return false
+	}
+	return !slices.ContainsFunc(p.Avoid, same)
+}
+
+// --------------------------
+// Solver
+// --------------------------
+
+// solve searches each position for moveTime and prints whether the move
+// found solves it, then the solved count
+func solve(suite []testPosition, moveTime time.Duration, threads, hashMB int) {
+	tt := engine.NewTT(hashMB)
+	solved := 0
+	for i, pos := range suite {
+		tt.Clear()
+		searcher := &engine.Searcher{TT: tt, Threads: max(threads, 1)}
+		res := searcher.Search(pos.Board, maxSearchDepth, moveTime)
+
+		mark := "FAIL"
+		if pos.solved(res.BestMove) {
+			mark = "ok"
+			solved++
+		}
+		fmt.Printf("%4d  %-4s %-12s %-7s %s  D%d %6s\n",
+			i+1, mark, pos.ID, pos.Board.SAN(res.BestMove), expected(pos), res.Depth, scoreString(res))
+	}
+
+	fmt.Printf("\nsolved %d/%d at %s per position\n", solved, len(suite), moveTime)
+}
+
+// expected formats the position's bm and am moves as the EPD gave them
+func expected(p testPosition) string {
+	var parts []string
+	if len(p.Best) > 0 {
+		parts = append(parts, "bm "+sanList(p.Board, p.Best))
+	}
+	if len(p.Avoid) > 0 {
+		parts = append(parts, "am "+sanList(p.Board, p.Avoid))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func sanList(b *engine.Board, moves []engine.Move) string {
+	sans := make([]string, len(moves))
+	for i, m := range moves {
+		sans[i] = b.SAN(m)
+	}
+	return strings.Join(sans, " ")
+}
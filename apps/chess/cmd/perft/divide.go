@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+)
+
+// --------------------------
+// Reference engine
+// --------------------------
+
+// uciVariants maps our variants to UCI_Variant values
+var uciVariants = map[engine.Variant]string{
+	engine.VariantStandard:      "chess",
+	engine.VariantKingOfTheHill: "kingofthehill",
+	engine.VariantThreeCheck:    "3check",
+	engine.VariantAtomic:        "atomic",
+}
+
+// refEngine is a UCI engine that answers "go perft" with one "move: count"
+// line per root move (Stockfish, or an older build of cmd/uci)
+type refEngine struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Scanner
+}
+
+func startRef(path string) (*refEngine, error) {
+	cmd := exec.Command(path)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	r := &refEngine{cmd: cmd, in: in, out: bufio.NewScanner(out)}
+	if err := r.send("uci"); err != nil {
+		return nil, err
+	}
+	if _, err := r.waitFor("uciok"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *refEngine) send(format string, args ...any) error {
+	_, err := fmt.Fprintf(r.in, format+"\n", args...)
+	return err
+}
+
+// waitFor reads lines until one starts with prefix, returning those before
+func (r *refEngine) waitFor(prefix string) ([]string, error) {
+	var lines []string
+	for r.out.Scan() {
+		line := strings.TrimSpace(r.out.Text())
+		if strings.HasPrefix(line, prefix) {
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+	if err := r.out.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("reference engine exited before %q", prefix)
+}
+
+// divide returns the reference's leaf count below each root move of b
+func (r *refEngine) divide(b *engine.Board, depth int) (map[string]uint64, error) {
+	chess960 := "false"
+	if b.Chess960 {
+		chess960 = "true"
+	}
+	if err := r.send("setoption name UCI_Chess960 value %s", chess960); err != nil {
+		return nil, err
+	}
+	if b.Variant != engine.VariantStandard {
+		if err := r.send("setoption name UCI_Variant value %s", uciVariants[b.Variant]); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.send("position fen %s\ngo perft %d", b.FEN(), depth); err != nil {
+		return nil, err
+	}
+
+	lines, err := r.waitFor("Nodes searched")
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]uint64)
+	for _, line := range lines {
+		move, count, ok := strings.Cut(line, ":")
+		if !ok || strings.Contains(move, " ") {
+			continue // info lines and the like
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(count), 10, 64)
+		if err != nil {
+			continue
+		}
+		counts[move] = n
+	}
+	return counts, nil
+}
+
+func (r *refEngine) close() {
+	r.send("quit")
+	r.in.Close()
+	r.cmd.Wait()
+}
+
+// --------------------------
+// Divide
+// --------------------------
+
+// findDivergence walks down from b at depth, each time into the first
+// root move whose count disagrees, until it reaches the position where the
+// move lists themselves differ. Without a reference engine it checks
+// instead that each move's subtree counts the same from a fresh board set
+// up from the FEN, which catches make/unmake bugs, and prints the last
+// divide so it can be compared by hand.
+func findDivergence(w io.Writer, b *engine.Board, depth int, ref *refEngine) error {
+	var line []string
+	for ; depth >= 1; depth-- {
+		ours := b.PerftDivide(depth)
+
+		var theirs map[string]uint64
+		var err error
+		if ref != nil {
+			theirs, err = ref.divide(b, depth)
+		} else {
+			theirs, err = freshDivide(b, depth)
+		}
+		if err != nil {
+			return err
+		}
+
+		missing, extra := diffMoves(ours, theirs)
+		if len(missing) > 0 || len(extra) > 0 {
+			fmt.Fprintf(w, "  diverges after %q\n  position %s\n", strings.Join(line, " "), b.FEN())
+			if len(missing) > 0 {
+				fmt.Fprintf(w, "  missing moves: %s\n", strings.Join(missing, " "))
+			}
+			if len(extra) > 0 {
+				fmt.Fprintf(w, "  extra moves:   %s\n", strings.Join(extra, " "))
+			}
+			return nil
+		}
+
+		move, ok := firstMismatch(ours, theirs)
+		if !ok {
+			break
+		}
+		fmt.Fprintf(w, "  %-*s %s: %d, want %d\n", 2*len(line), "", move, ours[move], theirs[move])
+
+		m, err := b.ParseUCIMove(move)
+		if err != nil {
+			return err
+		}
+		b.MakeMove(m)
+		line = append(line, move)
+	}
+
+	if len(line) == 0 {
+		fmt.Fprintf(w, "  no diverging move found at %s\n", b.FEN())
+		printDivide(w, b.PerftDivide(max(depth, 1)))
+		return nil
+	}
+	fmt.Fprintf(w, "  diverges after %q\n  position %s\n", strings.Join(line, " "), b.FEN())
+	return nil
+}
+
+// freshDivide counts each root move's subtree on a board set up from the
+// FEN after the move, rather than by make/unmake
+func freshDivide(b *engine.Board, depth int) (map[string]uint64, error) {
+	counts := make(map[string]uint64)
+	for _, m := range b.LegalMoves() {
+		b.MakeMove(m)
+		fresh := &engine.Board{Variant: b.Variant}
+		err := fresh.SetFEN(b.FEN())
+		fresh.Chess960 = b.Chess960
+		b.UnapplyMove()
+		if err != nil {
+			return nil, fmt.Errorf("after %s: %w", b.UCI(m), err)
+		}
+		counts[b.UCI(m)] = fresh.Perft(depth - 1)
+	}
+	return counts, nil
+}
+
+// diffMoves returns the moves only theirs has and the moves only ours has
+func diffMoves(ours, theirs map[string]uint64) (missing, extra []string) {
+	for m := range theirs {
+		if _, ok := ours[m]; !ok {
+			missing = append(missing, m)
+		}
+	}
+	for m := range ours {
+		if _, ok := theirs[m]; !ok {
+			extra = append(extra, m)
+		}
+	}
+	slices.Sort(missing)
+	slices.Sort(extra)
+	return missing, extra
+}
+
+// firstMismatch returns the first move, in sorted order, counted differently
+func firstMismatch(ours, theirs map[string]uint64) (string, bool) {
+	moves := make([]string, 0, len(ours))
+	for m := range ours {
+		moves = append(moves, m)
+	}
+	slices.Sort(moves)
+	for _, m := range moves {
+		if ours[m] != theirs[m] {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+func printDivide(w io.Writer, counts map[string]uint64) {
+	moves := make([]string, 0, len(counts))
+	for m := range counts {
+		moves = append(moves, m)
+	}
+	slices.Sort(moves)
+	for _, m := range moves {
+		fmt.Fprintf(w, "    %s: %d\n", m, counts[m])
+	}
+}
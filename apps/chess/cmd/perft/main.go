@@ -0,0 +1,202 @@
+// Command perft runs EPD perft suites against the move generator, in
+// parallel, and reports node counts, timings and nodes per second. When a
+// count is wrong it divides down to the first move that diverges, against
+// a reference UCI engine if one is given (-ref) or else by recounting each
+// subtree from a freshly set up board.
+//
+//	go run ./cmd/perft engine/testdata/perft.epd
+//	go run ./cmd/perft -variant atomic -depth 4 engine/testdata/perftatomic.epd
+//	go run ./cmd/perft -ref stockfish engine/testdata/perft960.epd
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+)
+
+func main() {
+	maxDepth := flag.Int("depth", 0, "deepest depth to check (0 for every depth in the suite)")
+	workers := flag.Int("j", runtime.NumCPU(), "positions to run in parallel")
+	variantName := flag.String("variant", "standard", "rules the suite is for (standard, kingofthehill, 3check, atomic)")
+	useTT := flag.Bool("tt", false, "count with a perft transposition table")
+	refPath := flag.String("ref", "", "reference UCI engine that supports \"go perft\", to divide against")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: perft [flags] suite.epd...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	variant, err := engine.ParseVariant(*variantName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var suite []engine.PerftPosition
+	for _, path := range flag.Args() {
+		positions, err := engine.ReadPerftSuite(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		suite = append(suite, positions...)
+	}
+
+	r := runner{variant: variant, maxDepth: *maxDepth, useTT: *useTT}
+	if !r.run(suite, max(*workers, 1), *refPath) {
+		os.Exit(1)
+	}
+}
+
+// --------------------------
+// Runner
+// --------------------------
+
+type runner struct {
+	variant  engine.Variant
+	maxDepth int
+	useTT    bool
+}
+
+// result is the outcome of one suite position. Depth is the last depth
+// counted: the first wrong one on failure, else the deepest.
+type result struct {
+	Depth   int
+	Nodes   uint64
+	Want    uint64
+	Elapsed time.Duration
+	Err     error
+}
+
+func (r result) ok() bool {
+	return r.Err == nil && r.Nodes == r.Want
+}
+
+// run checks every position, printing results in suite order as they
+// become available, then divides each failure. It reports whether all
+// positions passed.
+func (r runner) run(suite []engine.PerftPosition, workers int, refPath string) bool {
+	results := make([]chan result, len(suite))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] <- r.check(suite[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range suite {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	start := time.Now()
+	done := make([]result, len(suite))
+	var failed, wrong []int // wrong counts, which get divided
+	var total uint64
+	var busy time.Duration
+	for i, pos := range suite {
+		res := <-results[i]
+		done[i] = res
+		total += res.Nodes
+		busy += res.Elapsed
+
+		switch {
+		case res.Err != nil:
+			failed = append(failed, i)
+			fmt.Printf("%3d  ERROR  %v  %s\n", i+1, res.Err, pos.FEN)
+		case res.ok():
+			fmt.Printf("%3d  ok     D%-2d %14d  %9s  %7.2f Mnps  %s\n",
+				i+1, res.Depth, res.Nodes, res.Elapsed.Round(time.Millisecond), mnps(res.Nodes, res.Elapsed), pos.FEN)
+		default:
+			failed = append(failed, i)
+			wrong = append(wrong, i)
+			fmt.Printf("%3d  FAIL   D%-2d %14d  want %d  %s\n", i+1, res.Depth, res.Nodes, res.Want, pos.FEN)
+		}
+	}
+	wg.Wait()
+	wall := time.Since(start)
+
+	fmt.Printf("\n%d/%d passed, %d nodes in %s (%.2f Mnps, %.2f Mnps per worker)\n",
+		len(suite)-len(failed), len(suite), total, wall.Round(time.Millisecond), mnps(total, wall), mnps(total, busy))
+	if len(wrong) == 0 {
+		return len(failed) == 0
+	}
+
+	var ref *refEngine
+	if refPath != "" {
+		var err error
+		if ref, err = startRef(refPath); err != nil {
+			fmt.Fprintf(os.Stderr, "reference engine: %v\n", err)
+			return false
+		}
+		defer ref.close()
+	}
+	for _, i := range wrong {
+		fmt.Printf("\n%3d  D%d  %s\n", i+1, done[i].Depth, suite[i].FEN)
+		b, _ := r.board(suite[i].FEN)
+		if err := findDivergence(os.Stdout, b, done[i].Depth, ref); err != nil {
+			fmt.Fprintf(os.Stderr, "divide: %v\n", err)
+		}
+	}
+	return false
+}
+
+// check counts each of the position's depths in turn, stopping at the
+// first wrong one
+func (r runner) check(pos engine.PerftPosition) result {
+	b, err := r.board(pos.FEN)
+	if err != nil {
+		return result{Err: err}
+	}
+
+	var res result
+	for _, d := range pos.Depths(r.maxDepth) {
+		start := time.Now()
+		var nodes uint64
+		if r.useTT {
+			nodes = b.PerftTT(d, make(engine.PerftTT))
+		} else {
+			nodes = b.Perft(d)
+		}
+		res = result{Depth: d, Nodes: nodes, Want: pos.Nodes[d], Elapsed: res.Elapsed + time.Since(start)}
+		if nodes != res.Want {
+			break
+		}
+	}
+	return res
+}
+
+func (r runner) board(fen string) (*engine.Board, error) {
+	b := &engine.Board{Variant: r.variant}
+	if err := b.SetFEN(fen); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func mnps(nodes uint64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(nodes) / d.Seconds() / 1e6
+}
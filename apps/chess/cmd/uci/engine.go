@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+)
+
+const (
+	defaultHashMB = 64
+	maxHashMB     = 4096
+	maxThreads    = 256
+	maxMultiPV    = 64
+
+	defaultBookDepth = 16
+	maxBookDepth     = 256
+
+	maxSearchDepth = 64
+	infiniteTime   = 24 * time.Hour
+)
+
+// --------------------------
+// Engine state
+// --------------------------
+
+type uciEngine struct {
+	out     *syncWriter
+	board   *engine.Board
+	tt      *engine.TranspositionTable
+	threads int
+	multiPV int
+
+	ownBook   bool
+	book      *engine.Book // loaded from the BookFile option
+	bookDepth int
+
+	tb *engine.Tablebase // loaded from the SyzygyPath option
+
+	chess960 bool           // UCI_Chess960: castling is sent as king takes rook
+	variant  engine.Variant // UCI_Variant
+
+	cancel   context.CancelFunc
+	done     chan struct{} // closed when the running search has printed bestmove
+	infinite bool          // running search only ends on "stop"
+}
+
+func newUCIEngine(w io.Writer) *uciEngine {
+	return &uciEngine{
+		out:       &syncWriter{w: w},
+		board:     engine.NewBoard(),
+		tt:        engine.NewTT(defaultHashMB),
+		threads:   1,
+		multiPV:   1,
+		bookDepth: defaultBookDepth,
+	}
+}
+
+func (e *uciEngine) newGame() {
+	e.wait()
+	e.board = engine.NewBoard()
+	e.board.Chess960 = e.chess960
+	e.board.SetVariant(e.variant)
+	e.tt.Clear()
+}
+
+// --------------------------
+// setoption name <id> [value <x>]
+// --------------------------
+
+func (e *uciEngine) setOption(args []string) {
+	var name, value []string
+	target := &name
+	for _, a := range args {
+		switch a {
+		case "name":
+			target = &name
+		case "value":
+			target = &value
+		default:
+			*target = append(*target, a)
+		}
+	}
+
+	switch strings.ToLower(strings.Join(name, " ")) {
+	case "hash":
+		mb, err := strconv.Atoi(strings.Join(value, " "))
+		if err != nil || mb < 1 || mb > maxHashMB {
+			e.out.println("info string invalid Hash value")
+			return
+		}
+		e.wait()
+		e.tt = engine.NewTT(mb)
+	case "threads":
+		n, err := strconv.Atoi(strings.Join(value, " "))
+		if err != nil || n < 1 || n > maxThreads {
+			e.out.println("info string invalid Threads value")
+			return
+		}
+		e.wait()
+		e.threads = n
+	case "multipv":
+		n, err := strconv.Atoi(strings.Join(value, " "))
+		if err != nil || n < 1 || n > maxMultiPV {
+			e.out.println("info string invalid MultiPV value")
+			return
+		}
+		e.wait()
+		e.multiPV = n
+	case "ownbook":
+		e.wait()
+		e.ownBook = strings.EqualFold(strings.Join(value, " "), "true")
+	case "bookfile":
+		path := strings.Join(value, " ")
+		e.wait()
+		if path == "" || path == "<empty>" {
+			e.book = nil
+			return
+		}
+		book, err := engine.LoadBook(path)
+		if err != nil {
+			e.out.println("info string cannot load book: %v", err)
+			return
+		}
+		book.MaxPly = e.bookDepth
+		e.book = book
+		e.out.println("info string loaded book %s with %d entries", path, book.Len())
+	case "bookdepth":
+		n, err := strconv.Atoi(strings.Join(value, " "))
+		if err != nil || n < 0 || n > maxBookDepth {
+			e.out.println("info string invalid BookDepth value")
+			return
+		}
+		e.wait()
+		e.bookDepth = n
+		if e.book != nil {
+			e.book.MaxPly = n
+		}
+	case "uci_chess960":
+		e.wait()
+		e.chess960 = strings.EqualFold(strings.Join(value, " "), "true")
+		e.board.Chess960 = e.chess960
+	case "uci_variant":
+		v, err := engine.ParseVariant(strings.Join(value, " "))
+		if err != nil {
+			e.out.println("info string %v", err)
+			return
+		}
+		e.wait()
+		e.variant = v
+		e.board.SetVariant(v)
+	case "syzygypath":
+		paths := strings.Join(value, " ")
+		e.wait()
+		if paths == "" || paths == "<empty>" {
+			e.tb = nil
+			return
+		}
+		tb, err := engine.LoadTablebase(paths)
+		if err != nil {
+			e.out.println("info string cannot load tablebases: %v", err)
+			return
+		}
+		e.tb = tb
+		e.out.println("info string found %d tablebases up to %d pieces", tb.Len(), tb.MaxPieces)
+	default:
+		e.out.println("info string unknown option %s", strings.Join(name, " "))
+	}
+}
+
+// --------------------------
+// position [startpos | fen <fen>] [moves <m1> ... <mn>]
+// --------------------------
+
+func (e *uciEngine) position(args []string) {
+	e.wait()
+
+	if len(args) == 0 {
+		return
+	}
+
+	var board *engine.Board
+	rest := args[1:]
+
+	switch args[0] {
+	case "startpos":
+		board = engine.NewBoard()
+		board.SetVariant(e.variant)
+	case "fen":
+		i := 0
+		for i < len(rest) && rest[i] != "moves" {
+			i++
+		}
+		board = &engine.Board{Variant: e.variant}
+		if err := board.SetFEN(strings.Join(rest[:i], " ")); err != nil {
+			e.out.println("info string %v", err)
+			return
+		}
+		rest = rest[i:]
+	default:
+		e.out.println("info string invalid position command")
+		return
+	}
+
+	// Castling notation follows the option, whatever the FEN looked like
+	board.Chess960 = e.chess960
+
+	if len(rest) > 0 && rest[0] == "moves" {
+		for _, s := range rest[1:] {
+			m, err := board.ParseUCIMove(s)
+			if err != nil {
+				e.out.println("info string %v", err)
+				return
+			}
+			board.MakeMove(m)
+		}
+	}
+
+	e.board = board
+}
+
+// --------------------------
+// go [depth n] [movetime ms] [wtime ms] [btime ms] [winc ms] [binc ms] [movestogo n] [infinite]
+// go perft n
+// --------------------------
+
+// perft prints the leaf count below each root move and the total, in the
+// format Stockfish uses, so the perft command can divide against it
+func (e *uciEngine) perft(depth int) {
+	divide := e.board.PerftDivide(depth)
+	moves := make([]string, 0, len(divide))
+	var total uint64
+	for m, n := range divide {
+		moves = append(moves, m)
+		total += n
+	}
+	slices.Sort(moves)
+	for _, m := range moves {
+		e.out.println("%s: %d", m, divide[m])
+	}
+	e.out.println("")
+	e.out.println("Nodes searched: %d", total)
+}
+
+func (e *uciEngine) goSearch(args []string) {
+	e.wait()
+
+	if len(args) == 2 && args[0] == "perft" {
+		if n, err := strconv.Atoi(args[1]); err == nil && n > 0 {
+			e.perft(n)
+		}
+		return
+	}
+
+	depth := maxSearchDepth
+	var moveTime, wtime, btime, winc, binc time.Duration
+	movesToGo := 0
+	infinite := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "infinite" {
+			infinite = true
+			continue
+		}
+		if i+1 >= len(args) {
+			break
+		}
+
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			continue
+		}
+		i++
+
+		ms := time.Duration(n) * time.Millisecond
+		switch arg {
+		case "depth":
+			depth = min(n, maxSearchDepth)
+		case "movetime":
+			moveTime = ms
+		case "wtime":
+			wtime = ms
+		case "btime":
+			btime = ms
+		case "winc", "inc":
+			winc = ms
+			if arg == "inc" {
+				binc = ms
+			}
+		case "binc":
+			binc = ms
+		case "movestogo":
+			movesToGo = n
+		}
+	}
+
+	// Time limit: explicit movetime, else derived from the clock
+	limit := infiniteTime
+	remaining, inc := wtime, winc
+	if e.board.SideToMove == engine.Black {
+		remaining, inc = btime, binc
+	}
+	switch {
+	case infinite:
+	case moveTime > 0:
+		limit = moveTime
+	case remaining > 0:
+		limit = engine.TimeBudget(remaining, inc, movesToGo)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	e.cancel = cancel
+	e.done = done
+	e.infinite = infinite
+
+	board := e.board.Clone()
+	searcher := &engine.Searcher{
+		TT:      e.tt,
+		Threads: e.threads,
+		MultiPV: e.multiPV,
+		OnInfo:  func(i engine.SearchInfo) { e.info(board, i) },
+		TB:      e.tb,
+	}
+	// Analysis ("go infinite") always searches
+	if e.ownBook && !infinite {
+		searcher.Book = e.book
+	}
+
+	go func() {
+		defer close(done)
+
+		res := searcher.SearchContext(ctx, board, depth, limit)
+
+		// "go infinite" must not answer before "stop"
+		if infinite {
+			<-ctx.Done()
+		}
+
+		e.out.println("bestmove %s", bestMove(board, res))
+	}()
+}
+
+// info streams one completed iteration of a root line from b
+func (e *uciEngine) info(b *engine.Board, i engine.SearchInfo) {
+	e.out.println("info depth %d seldepth %d multipv %d score %s nodes %d nps %d hashfull %d time %d pv %s",
+		i.Depth, i.SelDepth, i.MultiPV, formatScore(i), i.Nodes, i.NPS, i.Hashfull, i.Time.Milliseconds(), b.UCILine(i.PV))
+}
+
+// stop cancels the running search and waits for its bestmove
+func (e *uciEngine) stop() {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	e.wait()
+}
+
+// wait blocks until the running search has printed its bestmove. GUIs
+// never send position/go while searching, but piped scripts do, so those
+// commands queue behind the search instead of cutting it short. An
+// infinite search would never finish and is stopped instead.
+func (e *uciEngine) wait() {
+	if e.done == nil {
+		return
+	}
+	if e.infinite {
+		e.cancel()
+	}
+	<-e.done
+	e.cancel()
+	e.cancel = nil
+	e.done = nil
+	e.infinite = false
+}
+
+// --------------------------
+// Helpers
+// --------------------------
+
+// bestMove returns the search move, falling back to any legal move when
+// the search was stopped before completing depth 1
+func bestMove(b *engine.Board, res engine.SearchResult) string {
+	if res.Depth > 0 || res.Book || res.TBHit {
+		return b.UCI(res.BestMove)
+	}
+	if legal := b.LegalMoves(); len(legal) > 0 {
+		return b.UCI(legal[0])
+	}
+	return "0000"
+}
+
+// formatScore renders a score as "cp <n>" or "mate <moves>"
+func formatScore(i engine.SearchInfo) string {
+	if i.Mate != 0 {
+		return "mate " + strconv.Itoa(i.Mate)
+	}
+	return "cp " + strconv.Itoa(i.Score)
+}
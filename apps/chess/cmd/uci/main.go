@@ -0,0 +1,91 @@
+// Command uci runs the chess engine behind the Universal Chess Interface
+// protocol on stdin/stdout, for use in GUIs (Cute Chess, Arena, ...) and
+// engine-vs-engine tournaments.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	engineName   = "Synergy Chess"
+	engineAuthor = "lordson vimal"
+)
+
+func main() {
+	e := newUCIEngine(os.Stdout)
+	e.run(os.Stdin)
+}
+
+// --------------------------
+// Output
+// --------------------------
+
+// syncWriter serialises protocol lines written by the command loop and
+// the search goroutine
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (sw *syncWriter) println(format string, args ...any) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	fmt.Fprintf(sw.w, format+"\n", args...)
+}
+
+// --------------------------
+// Command loop
+// --------------------------
+
+func (e *uciEngine) run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uci":
+			e.out.println("id name %s", engineName)
+			e.out.println("id author %s", engineAuthor)
+			e.out.println("option name Hash type spin default %d min 1 max %d", defaultHashMB, maxHashMB)
+			e.out.println("option name Threads type spin default 1 min 1 max %d", maxThreads)
+			e.out.println("option name MultiPV type spin default 1 min 1 max %d", maxMultiPV)
+			e.out.println("option name OwnBook type check default false")
+			e.out.println("option name BookFile type string default <empty>")
+			e.out.println("option name BookDepth type spin default %d min 0 max %d", defaultBookDepth, maxBookDepth)
+			e.out.println("option name SyzygyPath type string default <empty>")
+			e.out.println("option name UCI_Chess960 type check default false")
+			e.out.println("option name UCI_Variant type combo default chess var chess var kingofthehill var 3check var atomic")
+			e.out.println("uciok")
+		case "isready":
+			e.out.println("readyok")
+		case "ucinewgame":
+			e.newGame()
+		case "setoption":
+			e.setOption(fields[1:])
+		case "position":
+			e.position(fields[1:])
+		case "go":
+			e.goSearch(fields[1:])
+		case "stop":
+			e.stop()
+		case "quit":
+			e.stop()
+			return
+		case "d":
+			// Non-standard: print the current position
+			e.out.println("%s", e.board.FEN())
+		default:
+			e.out.println("info string unknown command %s", fields[0])
+		}
+	}
+	e.wait()
+}
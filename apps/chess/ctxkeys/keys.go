@@ -2,8 +2,14 @@ package ctxkeys
 
 type storeKeyType struct{}
 type gameRepoKeyType struct{}
+type analysisRepoKeyType struct{}
+type reviewRepoKeyType struct{}
+type playerIDKeyType struct{}
 
 var (
-	StoreKey    = storeKeyType{}
-	GameRepoKey = gameRepoKeyType{}
+	StoreKey        = storeKeyType{}
+	GameRepoKey     = gameRepoKeyType{}
+	AnalysisRepoKey = analysisRepoKeyType{}
+	ReviewRepoKey   = reviewRepoKeyType{}
+	PlayerIDKey     = playerIDKeyType{}
 )
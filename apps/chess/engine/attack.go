@@ -27,21 +27,14 @@ func PawnAttacks(color Color, sq uint8) uint64 {
 	return attacks
 }
 
-// BishopAttacks generates all squares a bishop attacks from sq given occupancy
+// RookAttacks returns the squares a rook attacks from sq given occupancy
 func RookAttacks(sq uint8, occ uint64) uint64 {
-	// TODO: Implement magic bitboards for rooks - Current implementation is wrong
-	// m := RookMagics[sq]
-	// occ &= m.Mask
-	// index := (occ * m.Magic) >> m.Shift
-	// return RookTable[sq][index]
-	return rookAttacksOnTheFly(int(sq), occ)
+	m := &RookMagics[sq]
+	return RookTable[sq][((occ&m.Mask)*m.Magic)>>m.Shift]
 }
 
+// BishopAttacks returns the squares a bishop attacks from sq given occupancy
 func BishopAttacks(sq uint8, occ uint64) uint64 {
-	// TODO: Implement magic bitboards for bishops - Current implementation is wrong
-	// m := BishopMagics[sq]
-	// occ &= m.Mask
-	// index := (occ * m.Magic) >> m.Shift
-	// return BishopTable[sq][index]
-	return bishopAttacksOnTheFly(int(sq), occ)
+	m := &BishopMagics[sq]
+	return BishopTable[sq][((occ&m.Mask)*m.Magic)>>m.Shift]
 }
@@ -98,6 +98,14 @@ func init() {
 	}
 }
 
+// --------------------------
+// Square colour masks
+// --------------------------
+const (
+	LightSquares uint64 = 0x55AA55AA55AA55AA
+	DarkSquares  uint64 = ^LightSquares
+)
+
 // --------------------------
 // Rank, File, Diagonal masks
 // --------------------------
@@ -57,7 +57,12 @@ type MoveState struct {
 	PrevHalfMove  uint16
 	PrevFullMove  uint16
 	PrevHash      uint64
+	PrevChecks    [ColorNB]uint8
 	Flags         uint8 // MoveNormal, MoveCastle, MoveEP, MovePromo
+
+	// PrevPieces is the placement before an atomic capture, whose
+	// explosion a single captured piece cannot undo
+	PrevPieces *[ColorNB][PieceNB]uint64
 }
 
 // --------------------------
@@ -78,7 +83,20 @@ type Board struct {
 	// Castling rights: 0001=WK,0010=WQ,0100=BK,1000=BQ
 	Castling uint8
 
-	// En-passant square (0–63), 255 = none
+	// CastleRooks is the home square of the rook each castling right
+	// belongs to, in the same order (WK, WQ, BK, BQ)
+	CastleRooks [4]uint8
+
+	// Chess960 writes castling as the king taking its own rook in UCI, and
+	// castling rights as rook files (Shredder-FEN) in FEN
+	Chess960 bool
+
+	// Variant picks the rules; Checks counts the checks each side has
+	// given, which only Three-check uses
+	Variant Variant
+	Checks  [ColorNB]uint8
+
+	// En-passant square (0–63), NoSquare = none
 	EnPassant uint8
 
 	HalfMoveClock  uint16
@@ -98,41 +116,56 @@ func NewBoard() *Board {
 	return b
 }
 
+// Clone returns a deep copy of the board, including its move stack
+func (b *Board) Clone() *Board {
+	nb := *b
+	nb.MoveStack = append([]MoveState(nil), b.MoveStack...)
+	return &nb
+}
+
 // --------------------------
 // Reset board to initial position
 // --------------------------
 func (b *Board) Reset() {
+	b.setup(standardBackRank)
+	b.Chess960 = false
+}
+
+// setup places the pieces of an initial position with the given back rank
+// (a to h) and full castling rights
+func (b *Board) setup(backRank [8]Piece) {
+	b.Pieces = [ColorNB][PieceNB]uint64{}
+
 	// Pawns
 	b.Pieces[White][Pawn] = 0x000000000000FF00
 	b.Pieces[Black][Pawn] = 0x00FF000000000000
 
-	// Rooks
-	b.Pieces[White][Rook] = 0x0000000000000081
-	b.Pieces[Black][Rook] = 0x8100000000000000
-
-	// Knights
-	b.Pieces[White][Knight] = 0x0000000000000042
-	b.Pieces[Black][Knight] = 0x4200000000000000
-
-	// Bishops
-	b.Pieces[White][Bishop] = 0x0000000000000024
-	b.Pieces[Black][Bishop] = 0x2400000000000000
-
-	// Queens
-	b.Pieces[White][Queen] = 0x0000000000000008
-	b.Pieces[Black][Queen] = 0x0800000000000000
-
-	// Kings
-	b.Pieces[White][King] = 0x0000000000000010
-	b.Pieces[Black][King] = 0x1000000000000000
+	// Pieces, mirrored for black
+	for file, p := range backRank {
+		b.Pieces[White][p] |= bit(uint8(file))
+		b.Pieces[Black][p] |= bit(uint8(56 + file))
+	}
 
 	b.updateOccupancy()
 
+	// Castling rooks: the outer rooks, kingside first
+	rooks := b.Pieces[White][Rook]
+	b.CastleRooks = [4]uint8{
+		uint8(63 - bits.LeadingZeros64(rooks)),
+		uint8(bits.TrailingZeros64(rooks)),
+		uint8(63-bits.LeadingZeros64(rooks)) + 56,
+		uint8(bits.TrailingZeros64(rooks)) + 56,
+	}
+
 	b.SideToMove = White
 	b.Castling = 0b1111
-	b.EnPassant = 255
+	b.EnPassant = NoSquare
 	b.HalfMoveClock = 0
 	b.FullMoveNumber = 1
+	b.Checks = [ColorNB]uint8{}
+	b.MoveStack = nil
+
+	b.Hash = b.BoardHash()
 }
 
 // --------------------------
@@ -239,20 +272,9 @@ func (b *Board) ApplyMove(m Move) {
 	// Castling rook move
 	// --------------------
 	if m.Flags&MoveCastle != 0 {
-		switch m.To {
-		case 6: // White king side
-			b.Pieces[White][Rook] &^= bit(7)
-			b.Pieces[White][Rook] |= bit(5)
-		case 2: // White queen side
-			b.Pieces[White][Rook] &^= bit(0)
-			b.Pieces[White][Rook] |= bit(3)
-		case 62: // Black king side
-			b.Pieces[Black][Rook] &^= bit(63)
-			b.Pieces[Black][Rook] |= bit(61)
-		case 58: // Black queen side
-			b.Pieces[Black][Rook] &^= bit(56)
-			b.Pieces[Black][Rook] |= bit(59)
-		}
+		rookFrom, rookTo := b.castleRookSquares(m.To)
+		b.Pieces[color][Rook] &^= bit(rookFrom)
+		b.Pieces[color][Rook] |= bit(rookTo)
 	}
 
 	// --------------------
@@ -266,29 +288,21 @@ func (b *Board) ApplyMove(m Move) {
 		}
 	}
 
-	if moved == Rook || captured == Rook {
-		switch m.From {
-		case 63:
-			b.Castling &^= 0b0001
-		case 56:
-			b.Castling &^= 0b0010
-		case 7:
-			b.Castling &^= 0b0100
-		case 0:
-			b.Castling &^= 0b1000
-		}
-		switch m.To {
-		case 63:
-			b.Castling &^= 0b0001
-		case 56:
-			b.Castling &^= 0b0010
-		case 7:
-			b.Castling &^= 0b0100
-		case 0:
-			b.Castling &^= 0b1000
+	if (moved == Rook || captured == Rook) && m.Flags&MoveCastle == 0 {
+		for i, sq := range b.CastleRooks {
+			if sq == m.From || sq == m.To {
+				b.Castling &^= 1 << i
+			}
 		}
 	}
 
+	// --------------------
+	// Atomic explosion
+	// --------------------
+	if b.Variant == VariantAtomic && captured != NoPiece {
+		b.explode(m.To)
+	}
+
 	// --------------------
 	// En-passant square
 	// --------------------
@@ -317,6 +331,13 @@ func (b *Board) ApplyMove(m Move) {
 	b.updateOccupancy()
 }
 
+// castleRookSquares returns the rook's from/to squares for a castling
+// move, given the king's destination square (g or c file)
+func (b *Board) castleRookSquares(kingTo uint8) (uint8, uint8) {
+	i := castleIndex(kingTo)
+	return b.CastleRooks[i], castleRookTo[i]
+}
+
 // --------------------------
 // Unapply move (undo)
 // --------------------------
@@ -332,6 +353,32 @@ func (b *Board) UnapplyMove() {
 	color := b.SideToMove ^ 1 // The side that actually moved
 	opp := color ^ 1
 
+	// 1-4. Move the pieces back: an atomic capture restores them all
+	if state.PrevPieces != nil {
+		b.Pieces = *state.PrevPieces
+	} else {
+		b.unmovePieces(state, color, opp)
+	}
+
+	// 5. Recalculate occupancy
+	b.updateOccupancy()
+
+	// 6. Restore en passant, castling rights, half/full move counters
+	b.EnPassant = state.PrevEP
+	b.Castling = state.PrevCastling
+	b.HalfMoveClock = state.PrevHalfMove
+	b.FullMoveNumber = state.PrevFullMove
+	b.Checks = state.PrevChecks
+
+	// 7. Restore Zobrist hash
+	b.Hash = state.PrevHash
+
+	// 8. Flip side back
+	b.SideToMove ^= 1
+}
+
+// unmovePieces takes back the piece moves of state, played by color
+func (b *Board) unmovePieces(state MoveState, color, opp Color) {
 	// 1. Remove moving piece from destination
 	if state.Flags&MovePromo != 0 {
 		// remove promoted piece
@@ -362,36 +409,10 @@ func (b *Board) UnapplyMove() {
 
 	// 4. Restore rook for castling
 	if state.Flags&MoveCastle != 0 {
-		switch state.To {
-		case 62: // White kingside
-			b.Pieces[White][Rook] &^= 1 << 61
-			b.Pieces[White][Rook] |= 1 << 63
-		case 58: // White queenside
-			b.Pieces[White][Rook] &^= 1 << 59
-			b.Pieces[White][Rook] |= 1 << 56
-		case 6: // Black kingside
-			b.Pieces[Black][Rook] &^= 1 << 5
-			b.Pieces[Black][Rook] |= 1 << 7
-		case 2: // Black queenside
-			b.Pieces[Black][Rook] &^= 1 << 3
-			b.Pieces[Black][Rook] |= 1 << 0
-		}
+		rookFrom, rookTo := b.castleRookSquares(state.To)
+		b.Pieces[color][Rook] &^= bit(rookTo)
+		b.Pieces[color][Rook] |= bit(rookFrom)
 	}
-
-	// 5. Recalculate occupancy
-	b.updateOccupancy()
-
-	// 6. Restore en passant, castling rights, half/full move counters
-	b.EnPassant = state.PrevEP
-	b.Castling = state.PrevCastling
-	b.HalfMoveClock = state.PrevHalfMove
-	b.FullMoveNumber = state.PrevFullMove
-
-	// 7. Restore Zobrist hash
-	b.Hash = state.PrevHash
-
-	// 8. Flip side back
-	b.SideToMove ^= 1
 }
 
 // --------------------------
@@ -403,16 +424,35 @@ func (b *Board) MakeMove(m Move) bool {
 	captured := b.pieceOnSquare(m.To)
 	promoted := m.Promotion
 
-	// 2. Set move flags
+	// 2. Set move flags. Castling comes flagged from the generator, as a
+	// two-square king move, or as the king taking its own rook (Chess960).
+	twoSquares := (m.To == m.From+2 || m.To == m.From-2) && (m.To%8 == 2 || m.To%8 == 6)
+	castle := movingPiece == King && (m.Flags&MoveCastle != 0 || twoSquares)
+	if movingPiece == King && b.Pieces[b.SideToMove][Rook]&bit(m.To) != 0 {
+		i := b.castleRightOf(m.To)
+		if i < 0 {
+			return false
+		}
+		m.To = castleKingTo[i]
+		castle = true
+	}
+	if castle {
+		if b.Castling&(1<<castleIndex(m.To)) == 0 {
+			return false
+		}
+		captured = NoPiece
+	}
+
 	m.Flags = MoveNormal
 	if promoted != NoPiece {
 		m.Flags |= MovePromo
 	}
-	if movingPiece == King && (m.To == m.From+2 || m.To == m.From-2) {
+	if castle {
 		m.Flags |= MoveCastle
 	}
 	if movingPiece == Pawn && captured == NoPiece && m.From%8 != m.To%8 {
 		m.Flags |= MoveEP
+		captured = Pawn
 	}
 
 	// 3. Check legality efficiently
@@ -427,10 +467,21 @@ func (b *Board) MakeMove(m Move) bool {
 	prevHalf := b.HalfMoveClock
 	prevFull := b.FullMoveNumber
 	prevHash := b.Hash
+	prevChecks := b.Checks
+	prevEPKey := b.epKey(prevEP, prevSide)
+
+	var prevPieces *[ColorNB][PieceNB]uint64
+	exploded := b.Variant == VariantAtomic && captured != NoPiece
+	if exploded {
+		saved := b.Pieces
+		prevPieces = &saved
+	}
 
 	// 5. Apply move permanently
 	b.ApplyMove(m)
-	b.UpdateHash(m, prevSide, movingPiece, captured, prevCastling, prevEP)
+	if !exploded {
+		b.UpdateHash(m, prevSide, movingPiece, captured, prevCastling, prevEPKey)
+	}
 
 	// 6. Save MoveState
 	state := MoveState{
@@ -444,7 +495,9 @@ func (b *Board) MakeMove(m Move) bool {
 		PrevHalfMove:  prevHalf,
 		PrevFullMove:  prevFull,
 		PrevHash:      prevHash,
+		PrevChecks:    prevChecks,
 		Flags:         m.Flags,
+		PrevPieces:    prevPieces,
 	}
 	b.MoveStack = append(b.MoveStack, state)
 
@@ -456,6 +509,17 @@ func (b *Board) MakeMove(m Move) bool {
 		b.FullMoveNumber++
 	}
 
+	// 9. Variant state: an explosion can take any piece with it, so the
+	// hash is rebuilt; Three-check counts the checks given
+	if exploded {
+		b.Hash = b.BoardHash()
+	}
+	if b.Variant == VariantThreeCheck && b.IsKingInCheck(b.SideToMove) {
+		b.Hash ^= ZChecks[prevSide][b.Checks[prevSide]]
+		b.Checks[prevSide]++
+		b.Hash ^= ZChecks[prevSide][b.Checks[prevSide]]
+	}
+
 	return true
 }
 
@@ -465,9 +529,20 @@ func (b *Board) MakeMove(m Move) bool {
 func (b *Board) TryMove(m Move) bool {
 	temp := *b // shallow copy
 	temp.ApplyMove(m)
+	us := temp.SideToMove
+
+	// Atomic: blowing up one's own king is never legal, the enemy's always is
+	if temp.Variant == VariantAtomic {
+		if temp.Pieces[us][King] == 0 {
+			return false
+		}
+		if temp.Pieces[us^1][King] == 0 {
+			return true
+		}
+	}
 
 	// Check if king is safe
-	return !temp.IsKingInCheck(temp.SideToMove)
+	return !temp.IsKingInCheck(us)
 }
 
 // Helper: get piece on a square
@@ -535,19 +610,19 @@ func (b *Board) FEN() string {
 		fen.WriteString(" b ")
 	}
 
-	// 4. Castling rights
+	// 4. Castling rights, as rook files (Shredder-FEN) in Chess960
 	castling := ""
-	if b.Castling&0b0001 != 0 {
-		castling += "K"
-	}
-	if b.Castling&0b0010 != 0 {
-		castling += "Q"
-	}
-	if b.Castling&0b0100 != 0 {
-		castling += "k"
-	}
-	if b.Castling&0b1000 != 0 {
-		castling += "q"
+	for i, letter := range "KQkq" {
+		if b.Castling&(1<<i) == 0 {
+			continue
+		}
+		if b.Chess960 {
+			letter = 'A' + rune(b.CastleRooks[i]%8)
+			if i >= 2 {
+				letter += 'a' - 'A'
+			}
+		}
+		castling += string(letter)
 	}
 	if castling == "" {
 		castling = "-"
@@ -556,7 +631,7 @@ func (b *Board) FEN() string {
 	fen.WriteByte(' ')
 
 	// 5. En-passant square
-	if b.EnPassant != 255 {
+	if b.EnPassant != NoSquare {
 		file := b.EnPassant % 8
 		rank := b.EnPassant / 8
 		fen.WriteByte('a' + file)
@@ -566,6 +641,14 @@ func (b *Board) FEN() string {
 	}
 	fen.WriteByte(' ')
 
+	// Three-check: the checks each side has left
+	if b.Variant == VariantThreeCheck {
+		fen.WriteByte('0' + 3 - b.Checks[White])
+		fen.WriteByte('+')
+		fen.WriteByte('0' + 3 - b.Checks[Black])
+		fen.WriteByte(' ')
+	}
+
 	// 6. Halfmove and fullmove (manual conversion to bytes)
 	writeUint(&fen, uint64(b.HalfMoveClock))
 	fen.WriteByte(' ')
@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sort"
+)
+
+// --------------------
+// Polyglot opening book
+// --------------------
+
+// bookEntrySize is the size of one Polyglot entry: key u64, move u16,
+// weight u16, learn u32, all big-endian, sorted by key
+const bookEntrySize = 16
+
+type bookEntry struct {
+	key    uint64
+	move   uint16
+	weight uint16
+}
+
+// BookMove is a book move playable in the probed position
+type BookMove struct {
+	Move   Move
+	Weight int
+}
+
+// Book is an in-memory Polyglot opening book
+type Book struct {
+	entries []bookEntry
+
+	// MaxPly stops book use once this many half-moves have been played
+	// (0 for no limit)
+	MaxPly int
+}
+
+// LoadBook reads a Polyglot .bin file
+func LoadBook(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBook(f)
+}
+
+// ReadBook reads Polyglot entries until EOF
+func ReadBook(r io.Reader) (*Book, error) {
+	br := bufio.NewReader(r)
+	var buf [bookEntrySize]byte
+	bk := &Book{}
+	for {
+		_, err := io.ReadFull(br, buf[:])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("book: truncated entry %d", len(bk.entries))
+			}
+			return nil, err
+		}
+		bk.entries = append(bk.entries, bookEntry{
+			key:    binary.BigEndian.Uint64(buf[0:8]),
+			move:   binary.BigEndian.Uint16(buf[8:10]),
+			weight: binary.BigEndian.Uint16(buf[10:12]),
+		})
+	}
+
+	// Probing binary-searches the keys; books are sorted on disk, but a
+	// hand-made file may not be
+	byKey := func(i, j int) bool { return bk.entries[i].key < bk.entries[j].key }
+	if !sort.SliceIsSorted(bk.entries, byKey) {
+		sort.SliceStable(bk.entries, byKey)
+	}
+	return bk, nil
+}
+
+// Len returns the number of entries
+func (bk *Book) Len() int {
+	return len(bk.entries)
+}
+
+// Moves returns the legal book moves for b with their weights. Books are
+// for standard chess only.
+func (bk *Book) Moves(b *Board) []BookMove {
+	if b.Variant != VariantStandard {
+		return nil
+	}
+
+	key := PolyglotKey(b)
+	i := sort.Search(len(bk.entries), func(i int) bool { return bk.entries[i].key >= key })
+
+	var moves []BookMove
+	for ; i < len(bk.entries) && bk.entries[i].key == key; i++ {
+		m, err := b.ParseUCIMove(bk.decodeMove(b, bk.entries[i].move))
+		if err != nil {
+			continue // a key collision or a corrupt entry
+		}
+		moves = append(moves, BookMove{Move: m, Weight: int(bk.entries[i].weight)})
+	}
+	return moves
+}
+
+// Probe picks a book move for b at random, in proportion to the weights.
+// It returns false past MaxPly or when the position is not in the book.
+func (bk *Book) Probe(b *Board) (Move, bool) {
+	if bk.MaxPly > 0 && gamePly(b) >= bk.MaxPly {
+		return Move{}, false
+	}
+
+	moves := bk.Moves(b)
+	total := 0
+	for _, bm := range moves {
+		total += bm.Weight
+	}
+	if total == 0 {
+		return Move{}, false
+	}
+
+	n := rand.IntN(total)
+	for _, bm := range moves {
+		n -= bm.Weight
+		if n < 0 {
+			return bm.Move, true
+		}
+	}
+	return Move{}, false
+}
+
+// gamePly is the number of half-moves played since the game's first move
+func gamePly(b *Board) int {
+	return (int(b.FullMoveNumber)-1)*2 + int(b.SideToMove)
+}
+
+// decodeMove converts a Polyglot move to UCI. Bits from low to high: to
+// file, to row, from file, from row (3 each) and promotion (1=N ... 4=Q).
+// Castling is stored as the king capturing its own rook.
+func (bk *Book) decodeMove(b *Board, pm uint16) string {
+	to := uint8(pm&7) | uint8(pm>>3&7)<<3
+	from := uint8(pm>>6&7) | uint8(pm>>9&7)<<3
+
+	if _, piece, ok := b.PieceAt(from); ok && piece == King && from%8 == 4 && !b.Chess960 {
+		switch to % 8 {
+		case 7:
+			to = from + 2
+		case 0:
+			to = from - 2
+		}
+	}
+
+	s := squareName(from) + squareName(to)
+	if promo := pm >> 12 & 7; promo >= 1 && promo <= 4 {
+		s += string("nbrq"[promo-1])
+	}
+	return s
+}
+
+// --------------------
+// Polyglot hashing
+// --------------------
+
+// Offsets into polyglotRandom
+const (
+	polyglotCastle = 768
+	polyglotEP     = 772
+	polyglotTurn   = 780
+)
+
+// PolyglotKey hashes b the way Polyglot books do
+func PolyglotKey(b *Board) uint64 {
+	var key uint64
+
+	for c := Color(0); c < ColorNB; c++ {
+		for p := Piece(0); p < PieceNB; p++ {
+			// Polyglot orders pieces black pawn, white pawn, black knight, ...
+			kind := 2 * int(p)
+			if c == White {
+				kind++
+			}
+			bb := b.Pieces[c][p]
+			for bb != 0 {
+				sq := PopLSB(&bb)
+				key ^= polyglotRandom[64*kind+int(sq)]
+			}
+		}
+	}
+
+	// Our castling bits are WK, WQ, BK, BQ: the same order as Polyglot's
+	for i := 0; i < 4; i++ {
+		if b.Castling&(1<<i) != 0 {
+			key ^= polyglotRandom[polyglotCastle+i]
+		}
+	}
+
+	// The en-passant file only counts if a pawn can actually capture there
+	if b.EnPassant != NoSquare {
+		us := b.SideToMove
+		if PawnAttacks(us^1, b.EnPassant)&b.Pieces[us][Pawn] != 0 {
+			key ^= polyglotRandom[polyglotEP+int(b.EnPassant%8)]
+		}
+	}
+
+	if b.SideToMove == White {
+		key ^= polyglotRandom[polyglotTurn]
+	}
+	return key
+}
@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --------------------------
+// Initial positions
+// --------------------------
+
+var standardBackRank = [8]Piece{Rook, Knight, Bishop, Queen, King, Bishop, Knight, Rook}
+
+// Chess960Standard is the number of the standard position among the 960
+const Chess960Standard = 518
+
+// chess960Knights places the two knights on the five squares left after
+// the bishops and queen
+var chess960Knights = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+	{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960BackRank returns the white back rank (a to h) of start position
+// n (0-959) in the standard numbering, in which 518 is the usual RNBQKBNR
+func Chess960BackRank(n int) ([8]Piece, error) {
+	var rank [8]Piece
+	if n < 0 || n >= 960 {
+		return rank, fmt.Errorf("chess960: no start position %d", n)
+	}
+	for i := range rank {
+		rank[i] = NoPiece
+	}
+
+	// Bishops on opposite colors: first the light-squared one (b, d, f, h)
+	rank[2*(n%4)+1] = Bishop
+	n /= 4
+	rank[2*(n%4)] = Bishop
+	n /= 4
+
+	// The rest fill the empty squares in turn
+	empty := func() []int {
+		var files []int
+		for f, p := range rank {
+			if p == NoPiece {
+				files = append(files, f)
+			}
+		}
+		return files
+	}
+
+	rank[empty()[n%6]] = Queen
+	n /= 6
+
+	free := empty()
+	for _, k := range chess960Knights[n] {
+		rank[free[k]] = Knight
+	}
+
+	// The king between the rooks
+	free = empty()
+	rank[free[0]], rank[free[1]], rank[free[2]] = Rook, King, Rook
+	return rank, nil
+}
+
+// NewChess960Board returns start position n (0-959) of Chess960
+func NewChess960Board(n int) (*Board, error) {
+	b := &Board{}
+	if err := b.ResetChess960(n); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ResetChess960 sets up start position n (0-959) of Chess960
+func (b *Board) ResetChess960(n int) error {
+	backRank, err := Chess960BackRank(n)
+	if err != nil {
+		return err
+	}
+	b.setup(backRank)
+	b.Chess960 = true
+	return nil
+}
+
+// --------------------------
+// Castling
+// --------------------------
+
+// Castling ends with the king on the g or c file and the rook next to it
+// on the f or d file, wherever they started. Indexed like the castling
+// rights: WK, WQ, BK, BQ.
+var (
+	castleKingTo = [4]uint8{6, 2, 62, 58}
+	castleRookTo = [4]uint8{5, 3, 61, 59}
+)
+
+// castleIndex returns the castling right a castling move uses, given the
+// king's destination square
+func castleIndex(kingTo uint8) int {
+	i := 0
+	if kingTo%8 == 2 {
+		i = 1
+	}
+	if kingTo >= 56 {
+		i += 2
+	}
+	return i
+}
+
+// castleRightOf returns the castling right whose rook stands on sq, or -1
+func (b *Board) castleRightOf(sq uint8) int {
+	for i, rook := range b.CastleRooks {
+		if rook == sq && b.Castling&(1<<i) != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// castleMove returns the castling move for right i, if the squares both
+// pieces cross are empty and the king does not pass through check. Whether
+// the king ends in check is left to the legality test, as the rook may
+// have been shielding it.
+func (b *Board) castleMove(i int, kingFrom uint8, opp Color) (Move, bool) {
+	rookFrom := b.CastleRooks[i]
+	kingTo, rookTo := castleKingTo[i], castleRookTo[i]
+
+	others := b.All &^ bit(kingFrom) &^ bit(rookFrom)
+	if others&(rankSpan(kingFrom, kingTo)|rankSpan(rookFrom, rookTo)) != 0 {
+		return Move{}, false
+	}
+
+	for span := rankSpan(kingFrom, kingTo); span != 0; {
+		if b.attackedForKing(PopLSB(&span), opp) {
+			return Move{}, false
+		}
+	}
+	return Move{From: kingFrom, To: kingTo, Promotion: NoPiece, Flags: MoveCastle}, true
+}
+
+// rankSpan returns the squares from a to b inclusive, on one rank
+func rankSpan(a, b uint8) uint64 {
+	if a > b {
+		a, b = b, a
+	}
+	return (bit(b) << 1) - bit(a)
+}
+
+// --------------------------
+// Notation
+// --------------------------
+
+// UCI returns m in UCI notation. Chess960 castling is written as the king
+// taking its own rook, since the king may move one square or none.
+func (b *Board) UCI(m Move) string {
+	if b.Chess960 && m.IsCastle() {
+		rookFrom, _ := b.castleRookSquares(m.To)
+		return squareName(m.From) + squareName(rookFrom)
+	}
+	return m.ToUCI()
+}
+
+// UCILine renders moves as space-separated UCI moves in b's notation
+func (b *Board) UCILine(pv []Move) string {
+	parts := make([]string, len(pv))
+	for i, m := range pv {
+		parts[i] = b.UCI(m)
+	}
+	return strings.Join(parts, " ")
+}
+
+// MoveTarget returns the square a player picks to play m: the castling
+// rook in Chess960, where the king's own destination may be ambiguous
+func (b *Board) MoveTarget(m Move) uint8 {
+	if b.Chess960 && m.IsCastle() {
+		rookFrom, _ := b.castleRookSquares(m.To)
+		return rookFrom
+	}
+	return m.To
+}
@@ -0,0 +1,386 @@
+package engine
+
+import "math/bits"
+
+// --------------------
+// Tapered scores
+// --------------------
+
+// Score holds a middlegame and an endgame value. Evaluate blends the two by
+// game phase, so terms can matter differently as material comes off.
+type Score struct {
+	MG, EG int
+}
+
+// S is shorthand for a Score literal in weight tables
+func S(mg, eg int) Score {
+	return Score{MG: mg, EG: eg}
+}
+
+func (s *Score) add(o Score)         { s.MG += o.MG; s.EG += o.EG }
+func (s *Score) sub(o Score)         { s.MG -= o.MG; s.EG -= o.EG }
+func (s *Score) addN(o Score, n int) { s.MG += o.MG * n; s.EG += o.EG * n }
+func (s Score) taper(phase int) int  { return (s.MG*phase + s.EG*(totalPhase-phase)) / totalPhase }
+
+// Game phase: 24 with all minor and major pieces on the board, 0 with none
+const totalPhase = 24
+
+var phaseWeight = [PieceNB]int{
+	Knight: 1,
+	Bishop: 1,
+	Rook:   2,
+	Queen:  4,
+}
+
+// --------------------
+// Evaluation weights
+// --------------------
+
+// EvalWeights collects every evaluation term in one place so it can be
+// tuned as a whole. Piece-square tables are written from White's point of
+// view with a8 first, as they appear on a diagram.
+type EvalWeights struct {
+	Material [PieceNB]Score
+	PST      [PieceNB][64]Score
+
+	// Mobility is awarded per reachable square beyond MobilityBase
+	Mobility     [PieceNB]Score
+	MobilityBase [PieceNB]int
+
+	DoubledPawn  Score // per extra pawn on a file
+	IsolatedPawn Score
+	PassedPawn   [8]Score // by relative rank
+
+	BishopPair Score
+
+	// King safety: shelter pawns in front of the king, and pressure from
+	// enemy pieces attacking the squares around it
+	KingShelter      [8]int // by relative rank of the shelter pawn
+	KingAttackWeight [PieceNB]int
+	KingDanger       int // penalty per attack unit squared
+	KingDangerMax    int
+}
+
+// Weights are the evaluation weights used by Evaluate
+var Weights = EvalWeights{
+	Material: [PieceNB]Score{
+		Pawn:   S(90, 110),
+		Knight: S(320, 300),
+		Bishop: S(330, 310),
+		Rook:   S(480, 520),
+		Queen:  S(950, 950),
+	},
+
+	PST: [PieceNB][64]Score{
+		Pawn: pst(
+			[64]int{
+				0, 0, 0, 0, 0, 0, 0, 0,
+				50, 50, 50, 50, 50, 50, 50, 50,
+				10, 10, 20, 30, 30, 20, 10, 10,
+				5, 5, 10, 25, 25, 10, 5, 5,
+				0, 0, 0, 20, 20, 0, 0, 0,
+				5, -5, -10, 0, 0, -10, -5, 5,
+				5, 10, 10, -20, -20, 10, 10, 5,
+				0, 0, 0, 0, 0, 0, 0, 0,
+			},
+			[64]int{
+				0, 0, 0, 0, 0, 0, 0, 0,
+				60, 60, 60, 60, 60, 60, 60, 60,
+				40, 40, 40, 40, 40, 40, 40, 40,
+				25, 25, 25, 25, 25, 25, 25, 25,
+				15, 15, 15, 15, 15, 15, 15, 15,
+				5, 5, 5, 5, 5, 5, 5, 5,
+				0, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 0,
+			},
+		),
+		Knight: pst(knightPST, knightPST),
+		Bishop: pst(bishopPST, bishopPST),
+		Rook: pst(
+			[64]int{
+				0, 0, 0, 0, 0, 0, 0, 0,
+				5, 10, 10, 10, 10, 10, 10, 5,
+				-5, 0, 0, 0, 0, 0, 0, -5,
+				-5, 0, 0, 0, 0, 0, 0, -5,
+				-5, 0, 0, 0, 0, 0, 0, -5,
+				-5, 0, 0, 0, 0, 0, 0, -5,
+				-5, 0, 0, 0, 0, 0, 0, -5,
+				0, 0, 0, 5, 5, 0, 0, 0,
+			},
+			[64]int{},
+		),
+		Queen: pst(
+			[64]int{
+				-20, -10, -10, -5, -5, -10, -10, -20,
+				-10, 0, 0, 0, 0, 0, 0, -10,
+				-10, 0, 5, 5, 5, 5, 0, -10,
+				-5, 0, 5, 5, 5, 5, 0, -5,
+				0, 0, 5, 5, 5, 5, 0, -5,
+				-10, 5, 5, 5, 5, 5, 0, -10,
+				-10, 0, 5, 0, 0, 0, 0, -10,
+				-20, -10, -10, -5, -5, -10, -10, -20,
+			},
+			[64]int{
+				-20, -10, -10, -5, -5, -10, -10, -20,
+				-10, 0, 0, 0, 0, 0, 0, -10,
+				-10, 0, 5, 5, 5, 5, 0, -10,
+				-5, 0, 5, 10, 10, 5, 0, -5,
+				-5, 0, 5, 10, 10, 5, 0, -5,
+				-10, 0, 5, 5, 5, 5, 0, -10,
+				-10, 0, 0, 0, 0, 0, 0, -10,
+				-20, -10, -10, -5, -5, -10, -10, -20,
+			},
+		),
+		King: pst(
+			[64]int{
+				-30, -40, -40, -50, -50, -40, -40, -30,
+				-30, -40, -40, -50, -50, -40, -40, -30,
+				-30, -40, -40, -50, -50, -40, -40, -30,
+				-30, -40, -40, -50, -50, -40, -40, -30,
+				-20, -30, -30, -40, -40, -30, -30, -20,
+				-10, -20, -20, -20, -20, -20, -20, -10,
+				20, 20, 0, 0, 0, 0, 20, 20,
+				20, 30, 10, 0, 0, 10, 30, 20,
+			},
+			[64]int{
+				-50, -40, -30, -20, -20, -30, -40, -50,
+				-30, -20, -10, 0, 0, -10, -20, -30,
+				-30, -10, 20, 30, 30, 20, -10, -30,
+				-30, -10, 30, 40, 40, 30, -10, -30,
+				-30, -10, 30, 40, 40, 30, -10, -30,
+				-30, -10, 20, 30, 30, 20, -10, -30,
+				-30, -30, 0, 0, 0, 0, -30, -30,
+				-50, -30, -30, -30, -30, -30, -30, -50,
+			},
+		),
+	},
+
+	Mobility: [PieceNB]Score{
+		Knight: S(4, 4),
+		Bishop: S(5, 5),
+		Rook:   S(2, 4),
+		Queen:  S(1, 2),
+	},
+	MobilityBase: [PieceNB]int{
+		Knight: 4,
+		Bishop: 6,
+		Rook:   7,
+		Queen:  13,
+	},
+
+	DoubledPawn:  S(-10, -20),
+	IsolatedPawn: S(-10, -15),
+	PassedPawn: [8]Score{
+		S(0, 0), S(5, 10), S(10, 15), S(15, 25),
+		S(25, 45), S(40, 70), S(60, 110), S(0, 0),
+	},
+
+	BishopPair: S(30, 50),
+
+	KingShelter:      [8]int{0, 0, 12, 6, 0, 0, 0, 0},
+	KingAttackWeight: [PieceNB]int{Knight: 2, Bishop: 2, Rook: 3, Queen: 5},
+	KingDanger:       3,
+	KingDangerMax:    400,
+}
+
+var knightPST = [64]int{
+	-50, -40, -30, -30, -30, -30, -40, -50,
+	-40, -20, 0, 0, 0, 0, -20, -40,
+	-30, 0, 10, 15, 15, 10, 0, -30,
+	-30, 5, 15, 20, 20, 15, 5, -30,
+	-30, 0, 15, 20, 20, 15, 0, -30,
+	-30, 5, 10, 15, 15, 10, 5, -30,
+	-40, -20, 0, 5, 5, 0, -20, -40,
+	-50, -40, -30, -30, -30, -30, -40, -50,
+}
+
+var bishopPST = [64]int{
+	-20, -10, -10, -10, -10, -10, -10, -20,
+	-10, 0, 0, 0, 0, 0, 0, -10,
+	-10, 0, 5, 10, 10, 5, 0, -10,
+	-10, 5, 5, 10, 10, 5, 5, -10,
+	-10, 0, 10, 10, 10, 10, 0, -10,
+	-10, 10, 10, 10, 10, 10, 10, -10,
+	-10, 5, 0, 0, 0, 0, 5, -10,
+	-20, -10, -10, -10, -10, -10, -10, -20,
+}
+
+// pst zips middlegame and endgame tables
+func pst(mg, eg [64]int) (t [64]Score) {
+	for i := range t {
+		t[i] = S(mg[i], eg[i])
+	}
+	return t
+}
+
+// --------------------
+// Pawn structure masks
+// --------------------
+
+var (
+	adjacentFiles [8]uint64
+	passedMask    [ColorNB][64]uint64 // squares that must be free of enemy pawns
+)
+
+func init() {
+	for f := 0; f < 8; f++ {
+		if f > 0 {
+			adjacentFiles[f] |= FileMask[f-1]
+		}
+		if f < 7 {
+			adjacentFiles[f] |= FileMask[f+1]
+		}
+	}
+
+	for sq := 0; sq < 64; sq++ {
+		rank, file := sq/8, sq%8
+		span := FileMask[file] | adjacentFiles[file]
+		for r := rank + 1; r < 8; r++ {
+			passedMask[White][sq] |= span & RankMask[r]
+		}
+		for r := rank - 1; r >= 0; r-- {
+			passedMask[Black][sq] |= span & RankMask[r]
+		}
+	}
+}
+
+// pawnAttacksBB returns all squares attacked by the given pawns
+func pawnAttacksBB(color Color, pawns uint64) uint64 {
+	west := pawns &^ FileMask[0]
+	east := pawns &^ FileMask[7]
+	if color == White {
+		return west<<7 | east<<9
+	}
+	return west>>9 | east>>7
+}
+
+// relativeRank is the rank of sq as seen from color's side
+func relativeRank(color Color, sq uint8) int {
+	if color == White {
+		return int(sq / 8)
+	}
+	return 7 - int(sq/8)
+}
+
+// --------------------
+// Evaluation
+// --------------------
+
+// Evaluate returns a tapered static evaluation in centipawns from the side
+// to move's point of view
+func Evaluate(b *Board) int {
+	w := &Weights
+
+	score := b.evalSide(White, w)
+	score.sub(b.evalSide(Black, w))
+
+	phase := 0
+	for p := Knight; p <= Queen; p++ {
+		phase += phaseWeight[p] * bits.OnesCount64(b.Pieces[White][p]|b.Pieces[Black][p])
+	}
+	phase = min(phase, totalPhase)
+
+	eval := score.taper(phase)
+	if b.SideToMove == Black {
+		return -eval
+	}
+	return eval
+}
+
+// evalSide scores color's pieces, pawn structure and king safety
+func (b *Board) evalSide(color Color, w *EvalWeights) Score {
+	var s Score
+	opp := color ^ 1
+	own := b.Occupancy[color]
+	pawns := b.Pieces[color][Pawn]
+	enemyPawns := b.Pieces[opp][Pawn]
+	enemyPawnAttacks := pawnAttacksBB(opp, enemyPawns)
+
+	enemyKing := uint8(bits.TrailingZeros64(b.Pieces[opp][King]))
+	kingZone := KingAttacks[enemyKing] | bit(enemyKing)
+	attackUnits, attackers := 0, 0
+
+	for p := Pawn; p <= King; p++ {
+		for bb := b.Pieces[color][p]; bb != 0; bb &= bb - 1 {
+			sq := uint8(bits.TrailingZeros64(bb))
+
+			s.add(w.Material[p])
+			s.add(w.PST[p][pstIndex(color, sq)])
+
+			var attacks uint64
+			switch p {
+			case Knight:
+				attacks = KnightAttacks[sq]
+			case Bishop:
+				attacks = BishopAttacks(sq, b.All)
+			case Rook:
+				attacks = RookAttacks(sq, b.All)
+			case Queen:
+				attacks = BishopAttacks(sq, b.All) | RookAttacks(sq, b.All)
+			default:
+				continue
+			}
+
+			// Squares defended by enemy pawns are not counted as mobility
+			mobility := bits.OnesCount64(attacks &^ own &^ enemyPawnAttacks)
+			s.addN(w.Mobility[p], mobility-w.MobilityBase[p])
+
+			if attacks&kingZone != 0 {
+				attackers++
+				attackUnits += w.KingAttackWeight[p] * bits.OnesCount64(attacks&kingZone)
+			}
+		}
+	}
+
+	// Bishop pair
+	if bits.OnesCount64(b.Pieces[color][Bishop]) >= 2 {
+		s.add(w.BishopPair)
+	}
+
+	// Pawn structure
+	for f := 0; f < 8; f++ {
+		if n := bits.OnesCount64(pawns & FileMask[f]); n > 1 {
+			s.addN(w.DoubledPawn, n-1)
+		}
+	}
+	for bb := pawns; bb != 0; bb &= bb - 1 {
+		sq := uint8(bits.TrailingZeros64(bb))
+		if pawns&adjacentFiles[sq%8] == 0 {
+			s.add(w.IsolatedPawn)
+		}
+		if enemyPawns&passedMask[color][sq] == 0 {
+			s.add(w.PassedPawn[relativeRank(color, sq)])
+		}
+	}
+
+	// King safety: pressure on the enemy king counts for us. It fades
+	// with the phase like any middlegame term.
+	if attackers >= 2 {
+		s.MG += min(attackUnits*attackUnits*w.KingDanger/4, w.KingDangerMax)
+	}
+	s.MG += b.kingShelter(color, w)
+
+	return s
+}
+
+// kingShelter rewards pawns on the king's file and the files beside it
+func (b *Board) kingShelter(color Color, w *EvalWeights) int {
+	king := uint8(bits.TrailingZeros64(b.Pieces[color][King]))
+	files := FileMask[king%8] | adjacentFiles[king%8]
+
+	shelter := 0
+	for bb := b.Pieces[color][Pawn] & files; bb != 0; bb &= bb - 1 {
+		sq := uint8(bits.TrailingZeros64(bb))
+		shelter += w.KingShelter[relativeRank(color, sq)]
+	}
+	return shelter
+}
+
+// pstIndex maps a square to its piece-square table entry: tables are laid
+// out a8..h1 from White's view, and mirrored vertically for Black
+func pstIndex(color Color, sq uint8) uint8 {
+	if color == White {
+		return sq ^ 56
+	}
+	return sq
+}
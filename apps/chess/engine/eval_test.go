@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+// mirrorFEN flips the position top to bottom and swaps the colors, so that
+// a sound evaluation scores it exactly opposite for White
+func mirrorFEN(t *testing.T, fen string) string {
+	t.Helper()
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		t.Fatalf("short FEN %q", fen)
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	fields[0] = swapCase(strings.Join(ranks, "/"))
+
+	if fields[1] == "w" {
+		fields[1] = "b"
+	} else {
+		fields[1] = "w"
+	}
+
+	if fields[2] != "-" {
+		// Keep White's rights first, as FEN writes them
+		swapped := swapCase(fields[2])
+		white := strings.IndexFunc(swapped, func(r rune) bool { return r >= 'A' && r <= 'Z' })
+		if white > 0 {
+			swapped = swapped[white:] + swapped[:white]
+		}
+		fields[2] = swapped
+	}
+
+	if ep := fields[3]; ep != "-" {
+		fields[3] = string(ep[0]) + string('1'+'8'-ep[1])
+	}
+	return strings.Join(fields, " ")
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return r
+	}, s)
+}
+
+// whiteEval is Evaluate from White's point of view
+func whiteEval(t *testing.T, fen string) int {
+	t.Helper()
+	b, err := ParseFEN(fen)
+	if err != nil {
+		t.Fatalf("ParseFEN(%q): %v", fen, err)
+	}
+	if b.SideToMove == Black {
+		return -Evaluate(b)
+	}
+	return Evaluate(b)
+}
+
+func TestEvaluateMirrorSymmetry(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+	}{
+		{"start", StartFEN},
+		{"open game", "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"},
+		{"en passant", "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 4"},
+		{"doubled pawns", "4k3/pp3ppp/8/8/8/2P5/P1P2PPP/4K3 w - - 0 1"},
+		{"isolated pawns", "4k3/p1p2p1p/8/8/8/8/PP3PPP/4K3 b - - 0 1"},
+		{"passed pawns", "6k1/8/1P6/8/5p2/8/6K1/8 w - - 0 1"},
+		{"connected passers", "8/5k2/8/3PP3/8/8/p7/4K3 b - - 0 1"},
+		{"bishop pair", "2b1kb2/pppppppp/8/8/8/8/PPPPPPPP/1N2KN2 w - - 0 1"},
+		{"bishop pair against rook", "4k3/pppr1ppp/8/8/8/8/PPP2PPP/2B1KB2 b - - 0 1"},
+		{"castled king shelter", "r4rk1/ppp2ppp/2n5/8/8/8/PPP2P1P/2KR3R w - - 0 1"},
+		{"exposed king", "r1bq1rk1/ppp2ppp/2n5/3p4/3P2Q1/8/PPP3PP/RNB1K2R w KQ - 0 1"},
+		{"queens near the king", "6k1/5ppp/8/6Q1/8/8/q4PPP/6K1 b - - 0 1"},
+		{"rooks on open files", "3r2k1/p4ppp/8/8/8/8/P4PPP/2R3K1 w - - 0 1"},
+		{"endgame", "8/8/4k3/3p4/3P4/4K3/8/8 w - - 0 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mirrored := mirrorFEN(t, tt.fen)
+			got, want := whiteEval(t, tt.fen), -whiteEval(t, mirrored)
+			if got != want {
+				t.Errorf("eval %d, mirrored %q evals %d", got, mirrored, -want)
+			}
+		})
+	}
+}
+
+func TestMirrorFEN(t *testing.T) {
+	fen := "r3k2r/pp3ppp/8/3pP3/8/8/PP3PPP/R3K2R w Kq d6 0 1"
+	want := "r3k2r/pp3ppp/8/8/3Pp3/8/PP3PPP/R3K2R b Qk d3 0 1"
+	if got := mirrorFEN(t, fen); got != want {
+		t.Errorf("mirrorFEN = %q, want %q", got, want)
+	}
+	if got := mirrorFEN(t, want); got != fen {
+		t.Errorf("mirrorFEN twice = %q, want %q", got, fen)
+	}
+}
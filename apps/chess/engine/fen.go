@@ -0,0 +1,315 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// StartFEN is the FEN string of the standard initial position
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// ErrInvalidFEN is wrapped by every error returned from ParseFEN / SetFEN
+var ErrInvalidFEN = errors.New("invalid FEN")
+
+// FEN letters → piece
+var fenToPiece = map[byte]struct {
+	color Color
+	piece Piece
+}{
+	'P': {White, Pawn}, 'N': {White, Knight}, 'B': {White, Bishop},
+	'R': {White, Rook}, 'Q': {White, Queen}, 'K': {White, King},
+	'p': {Black, Pawn}, 'n': {Black, Knight}, 'b': {Black, Bishop},
+	'r': {Black, Rook}, 'q': {Black, Queen}, 'k': {Black, King},
+}
+
+// --------------------------
+// ParseFEN builds a new board from a FEN string
+// --------------------------
+func ParseFEN(fen string) (*Board, error) {
+	b := &Board{}
+	if err := b.SetFEN(fen); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// --------------------------
+// SetFEN loads a position into the board.
+// The board is left untouched if the FEN is invalid.
+// The halfmove and fullmove fields may be omitted (EPD style),
+// in which case they default to 0 and 1.
+// The board keeps its variant, except that a Three-check counter field
+// makes it a Three-check board.
+// --------------------------
+func (b *Board) SetFEN(fen string) error {
+	fields := strings.Fields(fen)
+
+	var nb Board
+	nb.Variant = b.Variant
+
+	// 0. Three-check counters
+	fields, checks, ok, err := cutChecks(fields)
+	if err != nil {
+		return err
+	}
+	if ok {
+		nb.Variant = VariantThreeCheck
+		nb.Checks = checks
+	}
+
+	if len(fields) != 4 && len(fields) != 6 {
+		return fenError("expected 6 fields, got %d", len(fields))
+	}
+
+	// 1. Piece placement
+	if err := nb.parsePlacement(fields[0]); err != nil {
+		return err
+	}
+
+	// 2. Side to move
+	switch fields[1] {
+	case "w":
+		nb.SideToMove = White
+	case "b":
+		nb.SideToMove = Black
+	default:
+		return fenError("invalid side to move %q", fields[1])
+	}
+
+	// 3. Castling rights
+	if err := nb.parseCastling(fields[2]); err != nil {
+		return err
+	}
+
+	// 4. En-passant square
+	if err := nb.parseEnPassant(fields[3]); err != nil {
+		return err
+	}
+
+	// 5. Halfmove clock / fullmove number
+	nb.HalfMoveClock = 0
+	nb.FullMoveNumber = 1
+	if len(fields) == 6 {
+		half, err := strconv.ParseUint(fields[4], 10, 16)
+		if err != nil {
+			return fenError("invalid halfmove clock %q", fields[4])
+		}
+		full, err := strconv.ParseUint(fields[5], 10, 16)
+		if err != nil || full == 0 {
+			return fenError("invalid fullmove number %q", fields[5])
+		}
+		nb.HalfMoveClock = uint16(half)
+		nb.FullMoveNumber = uint16(full)
+	}
+
+	// 6. The side that just moved cannot be left in check
+	if nb.IsKingInCheck(nb.SideToMove ^ 1) {
+		return fenError("side not to move is in check")
+	}
+
+	nb.Hash = nb.BoardHash()
+	*b = nb
+	return nil
+}
+
+// cutChecks removes a Three-check counter field from fields: the checks
+// each side has left ("3+3", after the en-passant square), or the checks
+// each has given ("+0+0", at the end). It returns the checks given.
+func cutChecks(fields []string) ([]string, [ColorNB]uint8, bool, error) {
+	var checks [ColorNB]uint8
+	i := -1
+	switch {
+	case len(fields) >= 5 && strings.Contains(fields[4], "+"):
+		i = 4
+	case len(fields) >= 5 && strings.HasPrefix(fields[len(fields)-1], "+"):
+		i = len(fields) - 1
+	default:
+		return fields, checks, false, nil
+	}
+
+	field := fields[i]
+	given := strings.HasPrefix(field, "+")
+	parts := strings.Split(strings.TrimPrefix(field, "+"), "+")
+	if len(parts) != 2 {
+		return nil, checks, false, fenError("invalid check counts %q", field)
+	}
+	for c, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 8)
+		if err != nil || n > 3 {
+			return nil, checks, false, fenError("invalid check counts %q", field)
+		}
+		if !given {
+			n = 3 - n
+		}
+		checks[c] = uint8(n)
+	}
+
+	rest := append(append([]string(nil), fields[:i]...), fields[i+1:]...)
+	return rest, checks, true, nil
+}
+
+func (b *Board) parsePlacement(s string) error {
+	ranks := strings.Split(s, "/")
+	if len(ranks) != 8 {
+		return fenError("piece placement has %d ranks, want 8", len(ranks))
+	}
+
+	for i, row := range ranks {
+		rank := 7 - i
+		file := 0
+		for j := 0; j < len(row); j++ {
+			ch := row[j]
+			if ch >= '1' && ch <= '8' {
+				file += int(ch - '0')
+				if file > 8 {
+					return fenError("rank %d has more than 8 squares", rank+1)
+				}
+				continue
+			}
+
+			cp, ok := fenToPiece[ch]
+			if !ok {
+				return fenError("invalid piece %q on rank %d", ch, rank+1)
+			}
+			if file >= 8 {
+				return fenError("rank %d has more than 8 squares", rank+1)
+			}
+			if cp.piece == Pawn && (rank == 0 || rank == 7) {
+				return fenError("pawn on rank %d", rank+1)
+			}
+
+			b.Pieces[cp.color][cp.piece] |= bit(uint8(rank*8 + file))
+			file++
+		}
+		if file != 8 {
+			return fenError("rank %d has %d squares, want 8", rank+1, file)
+		}
+	}
+
+	for c := Color(0); c < ColorNB; c++ {
+		if n := bits.OnesCount64(b.Pieces[c][King]); n != 1 {
+			return fenError("%s has %d kings, want 1", colorName(c), n)
+		}
+	}
+
+	b.updateOccupancy()
+	return nil
+}
+
+// parseCastling reads standard (KQkq), X-FEN and Shredder-FEN castling
+// rights. K and Q name the outermost rook on either side of the king; a
+// file letter names the rook directly, as Chess960 positions may need.
+func (b *Board) parseCastling(s string) error {
+	b.Castling = 0
+	b.CastleRooks = [4]uint8{}
+	b.Chess960 = false
+	if s == "-" {
+		return nil
+	}
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		color, backRank := White, uint8(0)
+		if ch >= 'a' {
+			color, backRank = Black, 56
+			ch -= 'a' - 'A'
+		}
+
+		kingBB := b.Pieces[color][King] & (0xFF << backRank)
+		rooks := b.Pieces[color][Rook] & (0xFF << backRank)
+		if kingBB == 0 {
+			return fenError("castling right %q without the king on its back rank", s[i])
+		}
+		kingSq := uint8(bits.TrailingZeros64(kingBB))
+
+		var rookSq uint8
+		switch {
+		case ch == 'K':
+			right := rooks &^ (bit(kingSq+1) - 1)
+			if right == 0 {
+				return fenError("castling right %q without a rook", s[i])
+			}
+			rookSq = uint8(63 - bits.LeadingZeros64(right))
+		case ch == 'Q':
+			left := rooks & (bit(kingSq) - 1)
+			if left == 0 {
+				return fenError("castling right %q without a rook", s[i])
+			}
+			rookSq = uint8(bits.TrailingZeros64(left))
+		case ch >= 'A' && ch <= 'H':
+			rookSq = backRank + ch - 'A'
+			if rooks&bit(rookSq) == 0 || rookSq == kingSq {
+				return fenError("castling right %q without a rook", s[i])
+			}
+			b.Chess960 = true
+		default:
+			return fenError("invalid castling right %q", s[i])
+		}
+
+		right := 2 * int(color)
+		if rookSq < kingSq {
+			right++
+		}
+		if b.Castling&(1<<right) != 0 {
+			return fenError("duplicate castling right %q", s[i])
+		}
+		b.Castling |= 1 << right
+		b.CastleRooks[right] = rookSq
+
+		// Anything but the usual squares needs Chess960 castling
+		if kingSq != backRank+4 || (rookSq != backRank && rookSq != backRank+7) {
+			b.Chess960 = true
+		}
+	}
+
+	return nil
+}
+
+func (b *Board) parseEnPassant(s string) error {
+	b.EnPassant = NoSquare
+	if s == "-" {
+		return nil
+	}
+
+	sq, ok := parseSquare(s)
+	if !ok {
+		return fenError("invalid en-passant square %q", s)
+	}
+
+	// The pawn that just made a double push sits in front of the ep square
+	wantRank, pawnSq := uint8(5), sq-8
+	if b.SideToMove == Black {
+		wantRank, pawnSq = 2, sq+8
+	}
+	if sq/8 != wantRank {
+		return fenError("en-passant square %s on wrong rank", s)
+	}
+	if b.Pieces[b.SideToMove^1][Pawn]&bit(pawnSq) == 0 || b.All&bit(sq) != 0 {
+		return fenError("en-passant square %s without a double-pushed pawn", s)
+	}
+
+	b.EnPassant = sq
+	return nil
+}
+
+// parseSquare converts algebraic notation ("e3") to a square index
+func parseSquare(s string) (uint8, bool) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return 0, false
+	}
+	return (s[1]-'1')*8 + (s[0] - 'a'), true
+}
+
+func colorName(c Color) string {
+	if c == White {
+		return "white"
+	}
+	return "black"
+}
+
+func fenError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidFEN, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"strings"
+	"time"
+)
+
+// --------------------
+// Search info
+// --------------------
+
+// PVLine is a root move's score and principal variation
+type PVLine struct {
+	Score int
+	PV    []Move
+}
+
+// SearchInfo describes one root line after a completed iteration, in the
+// shape of a UCI "info" line
+type SearchInfo struct {
+	Depth    int
+	SelDepth int
+	MultiPV  int // 1-based line number
+	Score    int // centipawns from the side to move's point of view
+	Mate     int // moves to mate, negative when being mated, 0 if none
+	Nodes    uint64
+	NPS      uint64
+	Time     time.Duration
+	Hashfull int // permille
+	PV       []Move
+}
+
+// PVString renders the line as space-separated UCI moves
+func (i SearchInfo) PVString() string {
+	return PVToUCI(i.PV)
+}
+
+// PVToUCI renders moves as space-separated UCI moves
+func PVToUCI(pv []Move) string {
+	parts := make([]string, len(pv))
+	for i, m := range pv {
+		parts[i] = m.ToUCI()
+	}
+	return strings.Join(parts, " ")
+}
+
+// --------------------
+// Helpers
+// --------------------
+
+// mateBound separates mate scores from ordinary evaluations
+const mateBound = MATE_SCORE - 1000
+
+// MateIn converts a score to moves until mate: positive when the side to
+// move mates, negative when it is mated, 0 for an ordinary score
+func MateIn(score int) int {
+	switch {
+	case score > mateBound:
+		return (MATE_SCORE - score + 1) / 2
+	case score < -mateBound:
+		return -(MATE_SCORE + score + 1) / 2
+	}
+	return 0
+}
+
+// extendPV completes a PV cut short by transposition-table hits, following
+// stored best moves while they are legal, up to depth moves
+func (s *Searcher) extendPV(b *Board, pv []Move, depth int) []Move {
+	replay := b.Clone()
+	for _, m := range pv {
+		replay.MakeMove(m)
+	}
+
+	for len(pv) < depth {
+		m, ok := s.TT.GetMove(replay.Hash)
+		if !ok || !replay.isLegal(m) {
+			break
+		}
+		replay.MakeMove(m)
+		pv = append(pv, m)
+
+		// Stop at repetitions so the walk cannot cycle
+		if replay.IsRepetition() {
+			break
+		}
+	}
+	return pv
+}
+
+// isLegal reports whether m is one of the legal moves in the position
+func (b *Board) isLegal(m Move) bool {
+	for _, l := range b.LegalMoves() {
+		if l == m {
+			return true
+		}
+	}
+	return false
+}
+
+func nps(nodes uint64, elapsed time.Duration) uint64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return uint64(float64(nodes) / elapsed.Seconds())
+}
@@ -1,6 +1,9 @@
 package engine
 
-import "math/bits"
+import (
+	"fmt"
+	"math/bits"
+)
 
 type Magic struct {
 	Mask    uint64
@@ -12,42 +15,44 @@ type Magic struct {
 var RookMagics [64]Magic
 var BishopMagics [64]Magic
 
+// Magic multipliers, verified collision-free for every blocker subset when
+// the tables are built
 var rookMagicNumbers = [64]uint64{
-	0xa8002c862a04105, 0x4210810052201, 0x4008130061341, 0x188109010120004,
-	0x100440b0120114, 0x120481003024, 0x80a4010004a10, 0x880104031220300,
-	0x80010c12020302, 0x8124812030900, 0x440a02a01104, 0x40a020110006040,
-	0x1108a8165140100, 0x201108069110040, 0x100444a00401100, 0x820800302020001,
-	0x2028a0040812200, 0x410822028420040, 0x4000820800a0050, 0x401020c004108,
-	0x400a02012001, 0x4004080120a0100, 0x408800001220040, 0x2080088201100,
-	0x202a00201048020, 0x410081010020004, 0x4010020102a00, 0x40210820c0041,
-	0x411082a040001, 0x40110408100040, 0x401000820802, 0x2044011001100,
-	0x40040801010c020, 0x400440801020401, 0x42021080040101, 0x2084101306802,
-	0x40840a0201008, 0x10008c01001080, 0x480d800061000, 0x210a4000411,
-	0x201c00a0280102, 0x2001000420004, 0x410082008020001, 0x40a030004008,
-	0x208008824008, 0x804010a0110001, 0x4220080201200, 0x2010802002401,
-	0x44100008020802, 0x2080600100042, 0x2201000408104, 0x420880104006005,
-	0x20820a004000, 0x400408100500a, 0x4004080120008, 0x1100082052000,
-	0x204000504812, 0x100044010011, 0x209800061020001, 0x210c08070100,
-	0x41005080602, 0x10028408000410, 0x40800204010040, 0x1008021020010,
+	0x1080004008801020, 0x840092002c03000, 0x1900200010400900, 0x880100008000480,
+	0x4200100420080200, 0x8100020100080400, 0x200040110886200, 0x200008040220411,
+	0x404800084400220, 0x401000402000, 0x86001081220440, 0x408800800100280,
+	0xa001201040820, 0x8848800200840080, 0x4001000100040200, 0x442000102105084,
+	0x9080010020804100, 0x40404000201009, 0x808010002009, 0x2200090021d00100,
+	0x8008008040080, 0x4004002010040, 0x11040008015042, 0xa0001768104,
+	0x800080204009, 0x2010004140002001, 0x9800200280100080, 0x1000100080080080,
+	0x50500500080100, 0x20080040080, 0xc10010400420810, 0x1040008200005104,
+	0x1808240088004a0, 0x882804004802000, 0x880402001001100, 0x2000210409001000,
+	0x2000480131001500, 0x800400800200, 0x2380c001003, 0x4600084882000431,
+	0x80002000504000, 0x300500020004002, 0x40408200220011, 0x10040008004040,
+	0x80004008080, 0x10040002008080, 0x2012004881020004, 0x8300842444820011,
+	0x88403882010200, 0x820400080210100, 0x110910040a00300, 0x801100280080480,
+	0x242009008200600, 0x1002000489500200, 0x40800200010080, 0x91800041000080,
+	0x209300488001, 0x4c1002414824001, 0x20020000b001041, 0x7000100004200901,
+	0x8002002004100802, 0x30010002084c0007, 0x888221800813004, 0x4000002840840112,
 }
 
 var bishopMagicNumbers = [64]uint64{
-	0x40040822862081, 0x40810a4108000, 0x200800840042001, 0x401600c80210200,
-	0x4060820c800a00, 0x480204028100402, 0x100808202020010, 0x24160080200080,
-	0x18044840008010, 0x108080840450010, 0x108002012020010, 0x80220201408140,
-	0x803888004452a0, 0x202c0084161004, 0x10010041004002, 0x20400021008010,
-	0x80402040080810, 0x201000820101100, 0x20200802401010, 0x10010082020420,
-	0x20010b00100104, 0x4021082080020, 0x41008210400a00, 0x40008082004400,
-	0x810102100020, 0x2104448080100, 0x40400801200802, 0x220800c010c080,
-	0x8100408010, 0x4010008008041, 0x40801062000, 0x8200400101100,
-	0x82a08120401, 0x80040080800201, 0x2010806102000, 0x210081008221,
-	0x800108220200, 0x80010081000, 0x204081000402, 0x101000208200,
-	0x810a0300102001, 0x40028c200600, 0x20a0003040080, 0x841200080080,
-	0x4040001011002, 0x21000010081, 0x4002100111008, 0x80004040008010,
-	0x20001101000, 0x21001400802, 0x80a00108000, 0x4008081011002,
-	0x80040080800201, 0x1012a0120004, 0x410024000810, 0x40011000210200,
-	0x40820000044100, 0x11001028000, 0x401000820080, 0x1000010820044,
-	0x4002000408221, 0x4004010140c020, 0x20120020200, 0x8001020200102,
+	0x20c0090901061081, 0x24040094030104, 0x8210810200290200, 0x11040484620000,
+	0x81104002221000, 0x9012011001350, 0x81010802400380, 0x420210010408,
+	0x8105002280050, 0x1028484040044, 0x2a00880810408804, 0x7020022282000100,
+	0x84040420100a50, 0x401010840e000, 0x2020020210420888, 0x8084202012010,
+	0x2010400810018800, 0x445122008020840, 0x804100808002008, 0x8002104110100,
+	0x61005820080800, 0x2001000200820100, 0x480c210084010800, 0x3004442500480420,
+	0x1010102240048100, 0x182009084220a3, 0x8803090a10004205, 0x208080040202020,
+	0xc044084010040, 0xa1010002004106, 0x6008210020640202, 0x1600902112860801,
+	0x42008c1220200, 0x10c042002440140, 0x5022080200040820, 0x402004042940100,
+	0x860108400008020, 0xc080022021000, 0x264080652822100, 0x4005031221010401,
+	0x4502410008400, 0x500b010a20400, 0x415094050080800, 0x80000201800a104,
+	0x4022a80304000110, 0x4012140802028020, 0x40200104010100a0, 0x12810806008b0c41,
+	0x20441008080000, 0x2002120084045420, 0x704020062080002, 0x1084040001,
+	0x322200891240200, 0xf040200210024800, 0x140824832008042, 0x210020a004602,
+	0x83042805141020, 0x2c12009a011000, 0x41a00044140400, 0x4004020a0202,
+	0x140010020210, 0x2864160811012200, 0x2060080841082a17, 0xa010041108003100,
 }
 
 // Use these sizes for the Attack arrays to prevent out-of-bounds
@@ -56,8 +61,10 @@ var RookTable [64][4096]uint64
 var BishopTable [64][512]uint64
 
 func init() {
-	initRookMagics()
-	initBishopMagics()
+	for sq := 0; sq < 64; sq++ {
+		RookMagics[sq] = initMagic(sq, rookMask(sq), rookMagicNumbers[sq], rookAttacksOnTheFly, RookTable[sq][:])
+		BishopMagics[sq] = initMagic(sq, bishopMask(sq), bishopMagicNumbers[sq], bishopAttacksOnTheFly, BishopTable[sq][:])
+	}
 }
 
 func rookMask(sq int) uint64 {
@@ -98,48 +105,29 @@ func bishopMask(sq int) uint64 {
 	return mask
 }
 
-func initRookMagics() {
-	for sq := 0; sq < 64; sq++ {
-		mask := rookMask(sq)
-		numBits := bits.OnesCount64(mask)
-		size := 1 << numBits
-		magic := rookMagicNumbers[sq]
-
-		for i := 0; i < size; i++ {
-			occ := indexToOccupancy(i, mask)
-			index := (occ * magic) >> (64 - numBits)
-			// FIX: Store in global RookTable, not a local slice
-			RookTable[sq][index] = rookAttacksOnTheFly(sq, occ)
-		}
+// --------------------------
+// Table construction
+// --------------------------
 
-		RookMagics[sq] = Magic{
-			Mask:  mask,
-			Magic: magic,
-			Shift: uint(64 - numBits),
+// initMagic fills table with the attacks for every blocker subset of mask.
+// A destructive collision means the magic number is wrong.
+func initMagic(sq int, mask, magic uint64, attacksFn func(int, uint64) uint64, table []uint64) Magic {
+	numBits := bits.OnesCount64(mask)
+	shift := uint(64 - numBits)
+
+	used := make([]bool, 1<<numBits)
+	for i := range used {
+		occ := indexToOccupancy(i, mask)
+		attacks := attacksFn(sq, occ)
+		index := (occ * magic) >> shift
+		if used[index] && table[index] != attacks {
+			panic(fmt.Sprintf("magic: collision on square %d", sq))
 		}
+		used[index] = true
+		table[index] = attacks
 	}
-}
 
-func initBishopMagics() {
-	for sq := 0; sq < 64; sq++ {
-		mask := bishopMask(sq)
-		numBits := bits.OnesCount64(mask)
-		size := 1 << numBits
-		magic := bishopMagicNumbers[sq]
-
-		for i := 0; i < size; i++ {
-			occ := indexToOccupancy(i, mask)
-			// The shift must be exactly 64 - numBits
-			index := (occ * magic) >> (64 - numBits)
-			BishopTable[sq][index] = bishopAttacksOnTheFly(sq, occ)
-		}
-
-		BishopMagics[sq] = Magic{
-			Mask:  mask,
-			Magic: magic,
-			Shift: uint(64 - numBits),
-		}
-	}
+	return Magic{Mask: mask, Magic: magic, Shift: shift}
 }
 
 func indexToOccupancy(index int, mask uint64) uint64 {
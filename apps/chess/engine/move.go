@@ -1,6 +1,9 @@
 package engine
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // --------------------------
 // Move representation
@@ -127,3 +130,18 @@ func MoveFromUCI(s string) Move {
 
 	return Move{From: from, To: to, Promotion: promo, Flags: flags}
 }
+
+// ErrIllegalMove is returned when a move string matches no legal move
+var ErrIllegalMove = errors.New("illegal move")
+
+// ParseUCIMove resolves a UCI string (e2e4, e7e8q) against the legal moves
+// of the position, in the board's castling notation (see UCI). Unlike
+// MoveFromUCI it never panics on bad input.
+func (b *Board) ParseUCIMove(s string) (Move, error) {
+	for _, m := range b.LegalMoves() {
+		if b.UCI(m) == s {
+			return m, nil
+		}
+	}
+	return Move{}, fmt.Errorf("%w: %q", ErrIllegalMove, s)
+}
@@ -40,7 +40,7 @@ func (b *Board) PerftDivide(depth int) map[string]uint64 {
 		count := b.Perft(depth - 1)
 		b.UnapplyMove()
 
-		results[m.ToUCI()] = count
+		results[b.UCI(m)] = count
 	}
 
 	return results
@@ -91,7 +91,7 @@ func (b *Board) PerftDivideTT(depth int) map[string]uint64 {
 		nodes := b.PerftTT(depth-1, tt)
 		b.UnapplyMove()
 
-		results[m.ToUCI()] = nodes
+		results[b.UCI(m)] = nodes
 	}
 
 	return results
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// perftDepth is the deepest depth checked. cmd/perft runs the suites in
+// full; here they are cut short to keep go test quick.
+func perftDepth() int {
+	if testing.Short() {
+		return 2
+	}
+	return 4
+}
+
+// checkPerftSuite counts every position of the suite under variant's rules
+// to perftDepth, failing on the first wrong count of each
+func checkPerftSuite(t *testing.T, name string, variant Variant) {
+	suite, err := ReadPerftSuite(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pos := range suite {
+		t.Run(strconv.Itoa(pos.Line), func(t *testing.T) {
+			t.Parallel()
+			b := &Board{}
+			b.SetVariant(variant)
+			if err := b.SetFEN(pos.FEN); err != nil {
+				t.Fatalf("SetFEN(%q): %v", pos.FEN, err)
+			}
+			for _, depth := range pos.Depths(perftDepth()) {
+				want := pos.Nodes[depth]
+				if got := b.Perft(depth); got != want {
+					t.Fatalf("%s depth %d: %d nodes, want %d", pos.FEN, depth, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPerftStandard(t *testing.T) {
+	checkPerftSuite(t, "perft.epd", VariantStandard)
+}
+
+func TestPerftChess960(t *testing.T) {
+	checkPerftSuite(t, "perft960.epd", VariantStandard)
+}
+
+func TestPerftVariants(t *testing.T) {
+	tests := []struct {
+		suite   string
+		variant Variant
+	}{
+		{"perftkoth.epd", VariantKingOfTheHill},
+		{"perft3check.epd", VariantThreeCheck},
+		{"perftatomic.epd", VariantAtomic},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.variant.String(), func(t *testing.T) {
+			checkPerftSuite(t, tt.suite, tt.variant)
+		})
+	}
+}
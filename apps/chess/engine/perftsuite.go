@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// --------------------------
+// EPD perft suites
+// --------------------------
+
+// PerftPosition is one suite entry: a FEN and the expected leaf count at
+// each depth the suite gives
+type PerftPosition struct {
+	Line  int
+	FEN   string
+	Nodes map[int]uint64
+}
+
+// Depths returns the depths with an expected count, up to maxDepth (0 for
+// no limit), in increasing order
+func (p PerftPosition) Depths(maxDepth int) []int {
+	var ds []int
+	for d := range p.Nodes {
+		if maxDepth == 0 || d <= maxDepth {
+			ds = append(ds, d)
+		}
+	}
+	slices.Sort(ds)
+	return ds
+}
+
+// ReadPerftSuite parses a perft suite, one position per line:
+//
+//	<fen> ;D1 20 ;D2 400 ;D3 8902
+//
+// Blank lines and lines starting with # are skipped.
+func ReadPerftSuite(path string) ([]PerftPosition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var suite []PerftPosition
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, ";")
+		pos := PerftPosition{Line: n, FEN: strings.TrimSpace(parts[0]), Nodes: map[int]uint64{}}
+		for _, op := range parts[1:] {
+			fields := strings.Fields(op)
+			if len(fields) != 2 || !strings.HasPrefix(fields[0], "D") {
+				return nil, fmt.Errorf("%s:%d: invalid depth entry %q", path, n, strings.TrimSpace(op))
+			}
+			depth, err := strconv.Atoi(fields[0][1:])
+			if err != nil || depth < 1 {
+				return nil, fmt.Errorf("%s:%d: invalid depth %q", path, n, fields[0])
+			}
+			nodes, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid node count %q", path, n, fields[1])
+			}
+			pos.Nodes[depth] = nodes
+		}
+		if len(pos.Nodes) == 0 {
+			return nil, fmt.Errorf("%s:%d: no node counts", path, n)
+		}
+		suite = append(suite, pos)
+	}
+	return suite, sc.Err()
+}
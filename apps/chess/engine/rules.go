@@ -37,6 +37,10 @@ func (b *Board) squareAttacked(sq uint8, by Color) bool {
 // Generate pseudo-legal moves
 // --------------------------
 func (b *Board) GeneratePseudoLegalMoves() []Move {
+	if b.IsVariantLoss() {
+		return nil // the game is over
+	}
+
 	var moves []Move
 	color := b.SideToMove
 	opp := color ^ 1
@@ -90,14 +94,14 @@ func (b *Board) generatePawnMoves(sq uint8, color, opp Color) []Move {
 				moves = append(moves, Move{From: sq, To: uint8(to), Promotion: promo, Flags: MovePromo})
 			}
 		} else {
-			moves = append(moves, Move{From: sq, To: uint8(to), Flags: uint8(flag)})
+			moves = append(moves, Move{From: sq, To: uint8(to), Promotion: NoPiece, Flags: uint8(flag)})
 		}
 
 		// Double move
 		if sq/8 == startRank {
 			to2 := int(sq) + int(forward*2)
 			if to2 >= 0 && to2 < 64 && (b.All&(1<<to2)) == 0 {
-				moves = append(moves, Move{From: sq, To: uint8(to2), Flags: MoveNormal})
+				moves = append(moves, Move{From: sq, To: uint8(to2), Promotion: NoPiece, Flags: MoveNormal})
 			}
 		}
 	}
@@ -112,7 +116,7 @@ func (b *Board) generatePawnMoves(sq uint8, color, opp Color) []Move {
 				moves = append(moves, Move{From: sq, To: to, Promotion: promo, Flags: MoveCapture | MovePromo})
 			}
 		} else {
-			moves = append(moves, Move{From: sq, To: to, Flags: uint8(flag)})
+			moves = append(moves, Move{From: sq, To: to, Promotion: NoPiece, Flags: uint8(flag)})
 		}
 	}
 
@@ -120,7 +124,7 @@ func (b *Board) generatePawnMoves(sq uint8, color, opp Color) []Move {
 	if b.EnPassant != NoSquare {
 		epSq := b.EnPassant
 		if PawnAttacks(color, sq)&(1<<epSq) != 0 {
-			moves = append(moves, Move{From: sq, To: epSq, Flags: MoveEP})
+			moves = append(moves, Move{From: sq, To: epSq, Promotion: NoPiece, Flags: MoveEP})
 		}
 	}
 
@@ -143,9 +147,10 @@ func (b *Board) generateKnightMoves(sq uint8, color Color) []Move {
 		}
 
 		moves = append(moves, Move{
-			From:  sq,
-			To:    to,
-			Flags: uint8(flag),
+			From:      sq,
+			To:        to,
+			Promotion: NoPiece,
+			Flags:     uint8(flag),
 		})
 	}
 
@@ -168,9 +173,10 @@ func (b *Board) generateBishopMoves(sq uint8, color Color) []Move {
 		}
 
 		moves = append(moves, Move{
-			From:  sq,
-			To:    to,
-			Flags: uint8(flag),
+			From:      sq,
+			To:        to,
+			Promotion: NoPiece,
+			Flags:     uint8(flag),
 		})
 	}
 
@@ -193,9 +199,10 @@ func (b *Board) generateRookMoves(sq uint8, color Color) []Move {
 		}
 
 		moves = append(moves, Move{
-			From:  sq,
-			To:    to,
-			Flags: uint8(flag),
+			From:      sq,
+			To:        to,
+			Promotion: NoPiece,
+			Flags:     uint8(flag),
 		})
 	}
 
@@ -235,11 +242,12 @@ func (b *Board) generateKingMoves(sq uint8, color Color) []Move {
 		}
 
 		// Skip squares under attack
-		if !b.squareAttacked(to, opp) {
+		if !b.attackedForKing(to, opp) {
 			moves = append(moves, Move{
-				From:  sq,
-				To:    to,
-				Flags: uint8(flag),
+				From:      sq,
+				To:        to,
+				Promotion: NoPiece,
+				Flags:     uint8(flag),
 			})
 		}
 	}
@@ -247,43 +255,13 @@ func (b *Board) generateKingMoves(sq uint8, color Color) []Move {
 	// -----------------
 	// Castling
 	// -----------------
-	if color == White {
-		// White King is on sq 4 (e1)
-		// King side: e1 -> g1 (4 -> 6)
-		if b.Castling&0b0001 != 0 &&
-			b.All&(bit(5)|bit(6)) == 0 &&
-			!b.squareAttacked(4, Black) &&
-			!b.squareAttacked(5, Black) &&
-			!b.squareAttacked(6, Black) {
-			moves = append(moves, Move{From: 4, To: 6, Flags: MoveCastle})
-		}
-
-		// Queen side: e1 -> c1 (4 -> 2)
-		if b.Castling&0b0010 != 0 &&
-			b.All&(bit(1)|bit(2)|bit(3)) == 0 &&
-			!b.squareAttacked(4, Black) &&
-			!b.squareAttacked(3, Black) &&
-			!b.squareAttacked(2, Black) {
-			moves = append(moves, Move{From: 4, To: 2, Flags: MoveCastle})
-		}
-	} else {
-		// Black King is on sq 60 (e8)
-		// King side: e8 -> g8 (60 -> 62)
-		if b.Castling&0b0100 != 0 &&
-			b.All&(bit(61)|bit(62)) == 0 &&
-			!b.squareAttacked(60, White) &&
-			!b.squareAttacked(61, White) &&
-			!b.squareAttacked(62, White) {
-			moves = append(moves, Move{From: 60, To: 62, Flags: MoveCastle})
+	for side := 0; side < 2; side++ {
+		i := 2*int(color) + side
+		if b.Castling&(1<<i) == 0 {
+			continue
 		}
-
-		// Queen side: e8 -> c8 (60 -> 58)
-		if b.Castling&0b1000 != 0 &&
-			b.All&(bit(57)|bit(58)|bit(59)) == 0 &&
-			!b.squareAttacked(60, White) &&
-			!b.squareAttacked(59, White) &&
-			!b.squareAttacked(58, White) {
-			moves = append(moves, Move{From: 60, To: 58, Flags: MoveCastle})
+		if m, ok := b.castleMove(i, sq, opp); ok {
+			moves = append(moves, m)
 		}
 	}
 
@@ -303,7 +281,7 @@ func (b *Board) IsKingInCheck(color Color) bool {
 	}
 
 	kingSq := uint8(bits.TrailingZeros64(uint64(kingBB))) // position 0..63
-	return b.squareAttacked(kingSq, opp)
+	return b.attackedForKing(kingSq, opp)
 }
 
 // --------------------------
@@ -311,8 +289,8 @@ func (b *Board) IsKingInCheck(color Color) bool {
 // --------------------------
 func (b *Board) GenerateMovesForSquare(sq uint8) []Move {
 	color, piece, ok := b.PieceAt(sq)
-	if !ok || color != b.SideToMove {
-		return nil // no piece or not this side's turn
+	if !ok || color != b.SideToMove || b.IsVariantLoss() {
+		return nil // no piece, not this side's turn or game over
 	}
 
 	var moves []Move
@@ -345,6 +323,9 @@ func (b *Board) GenerateMovesForSquare(sq uint8) []Move {
 }
 
 func (b *Board) HasLegalMoves(color Color) bool {
+	if color == b.SideToMove && b.IsVariantLoss() {
+		return false
+	}
 	for sq := uint8(0); sq < 64; sq++ {
 		c, p, ok := b.PieceAt(sq)
 		if !ok || c != color {
@@ -377,6 +358,10 @@ func (b *Board) HasLegalMoves(color Color) bool {
 }
 
 func (b *Board) GenerateCaptures() []Move {
+	if b.IsVariantLoss() {
+		return nil
+	}
+
 	var moves []Move
 
 	color := b.SideToMove
@@ -411,9 +396,10 @@ func (b *Board) GenerateCaptures() []Move {
 				}
 			} else {
 				moves = append(moves, Move{
-					From:  sq,
-					To:    to,
-					Flags: MoveCapture,
+					From:      sq,
+					To:        to,
+					Promotion: NoPiece,
+					Flags:     MoveCapture,
 				})
 			}
 		}
@@ -423,9 +409,10 @@ func (b *Board) GenerateCaptures() []Move {
 			ep := b.EnPassant
 			if PawnAttacks(color, sq)&(1<<ep) != 0 {
 				moves = append(moves, Move{
-					From:  sq,
-					To:    ep,
-					Flags: MoveEP | MoveCapture,
+					From:      sq,
+					To:        ep,
+					Promotion: NoPiece,
+					Flags:     MoveEP | MoveCapture,
 				})
 			}
 		}
@@ -442,9 +429,10 @@ func (b *Board) GenerateCaptures() []Move {
 		for bb := attacks; bb != 0; {
 			to := PopLSB(&bb)
 			moves = append(moves, Move{
-				From:  sq,
-				To:    to,
-				Flags: MoveCapture,
+				From:      sq,
+				To:        to,
+				Promotion: NoPiece,
+				Flags:     MoveCapture,
 			})
 		}
 	}
@@ -460,9 +448,10 @@ func (b *Board) GenerateCaptures() []Move {
 		for bb := attacks; bb != 0; {
 			to := PopLSB(&bb)
 			moves = append(moves, Move{
-				From:  sq,
-				To:    to,
-				Flags: MoveCapture,
+				From:      sq,
+				To:        to,
+				Promotion: NoPiece,
+				Flags:     MoveCapture,
 			})
 		}
 	}
@@ -478,9 +467,10 @@ func (b *Board) GenerateCaptures() []Move {
 		for bb := attacks; bb != 0; {
 			to := PopLSB(&bb)
 			moves = append(moves, Move{
-				From:  sq,
-				To:    to,
-				Flags: MoveCapture,
+				From:      sq,
+				To:        to,
+				Promotion: NoPiece,
+				Flags:     MoveCapture,
 			})
 		}
 	}
@@ -496,9 +486,10 @@ func (b *Board) GenerateCaptures() []Move {
 		for bb := attacks; bb != 0; {
 			to := PopLSB(&bb)
 			moves = append(moves, Move{
-				From:  sq,
-				To:    to,
-				Flags: MoveCapture,
+				From:      sq,
+				To:        to,
+				Promotion: NoPiece,
+				Flags:     MoveCapture,
 			})
 		}
 	}
@@ -515,14 +506,15 @@ func (b *Board) GenerateCaptures() []Move {
 			to := PopLSB(&bb)
 
 			// Skip squares under attack
-			if b.squareAttacked(to, opp) {
+			if b.attackedForKing(to, opp) {
 				continue
 			}
 
 			moves = append(moves, Move{
-				From:  sq,
-				To:    to,
-				Flags: MoveCapture,
+				From:      sq,
+				To:        to,
+				Promotion: NoPiece,
+				Flags:     MoveCapture,
 			})
 		}
 	}
@@ -530,14 +522,102 @@ func (b *Board) GenerateCaptures() []Move {
 	return moves
 }
 
+// --------------------------
+// Repetition / move-count draws
+// --------------------------
+
+// RepetitionCount returns how many times the current position has occurred,
+// including the current occurrence. Only positions since the last
+// irreversible move (pawn move or capture) can repeat, so the MoveStack is
+// scanned back no further than HalfMoveClock plies, same side to move only.
+func (b *Board) RepetitionCount() int {
+	count := 1
+	n := len(b.MoveStack)
+	limit := int(b.HalfMoveClock)
+	for i := 2; i <= limit && i <= n; i += 2 {
+		if b.MoveStack[n-i].PrevHash == b.Hash {
+			count++
+		}
+	}
+	return count
+}
+
+// IsRepetition reports whether the current position occurred before
+func (b *Board) IsRepetition() bool {
+	return b.RepetitionCount() >= 2
+}
+
+// IsThreefoldRepetition reports whether a draw can be claimed by repetition
 func (b *Board) IsThreefoldRepetition() bool {
-	// Count how many times current position hash occurred
-	// You can store a map[uint64]int in Board or WAL for efficiency
-	return false
+	return b.RepetitionCount() >= 3
 }
 
+// IsFivefoldRepetition reports whether the game is drawn automatically by repetition
+func (b *Board) IsFivefoldRepetition() bool {
+	return b.RepetitionCount() >= 5
+}
+
+// IsFiftyMoveRule reports whether a draw can be claimed under the 50-move rule
+func (b *Board) IsFiftyMoveRule() bool {
+	return b.HalfMoveClock >= 100
+}
+
+// IsSeventyFiveMoveRule reports whether the game is drawn automatically under the 75-move rule
+func (b *Board) IsSeventyFiveMoveRule() bool {
+	return b.HalfMoveClock >= 150
+}
+
+// --------------------------
+// Insufficient material (FIDE dead positions)
+// --------------------------
+
+// HasMatingMaterial reports whether color could checkmate the opponent by
+// any series of legal moves (helpmates included). This is the FIDE test
+// used both for dead positions and for "timeout vs insufficient material".
+func (b *Board) HasMatingMaterial(color Color) bool {
+	// The variants win without mate: a king can always head for the hill,
+	// and any other piece can give check or set off an explosion
+	switch b.Variant {
+	case VariantKingOfTheHill:
+		return true
+	case VariantThreeCheck, VariantAtomic:
+		return b.Occupancy[color] != b.Pieces[color][King]
+	}
+
+	opp := color ^ 1
+	own := b.Pieces[color]
+	other := b.Pieces[opp]
+
+	// Any pawn, rook or queen can mate
+	if own[Pawn]|own[Rook]|own[Queen] != 0 {
+		return true
+	}
+
+	knights := bits.OnesCount64(own[Knight])
+	oppNonKing := b.Occupancy[opp] &^ other[King]
+
+	switch {
+	case knights == 0 && own[Bishop] == 0:
+		// Lone king
+		return false
+
+	case knights == 0:
+		// Bishops only: mate needs a piece able to block a square of the
+		// other colour, so all bishops on one colour and nothing else is dead
+		bishops := own[Bishop] | other[Bishop]
+		sameColor := bishops&LightSquares == 0 || bishops&DarkSquares == 0
+		return !(sameColor && oppNonKing&^other[Bishop] == 0)
+
+	case knights == 1 && own[Bishop] == 0:
+		// A single knight mates only with help from an opponent piece
+		return oppNonKing != 0
+	}
+
+	return true
+}
+
+// IsInsufficientMaterial reports whether neither side can checkmate
+// (K vs K, K+minor vs K, bishops all on squares of one colour, ...)
 func (b *Board) IsInsufficientMaterial() bool {
-	// Return true if neither side has enough material to checkmate
-	// Examples: K vs K, K+N vs K, K+B vs K
-	return false
+	return !b.HasMatingMaterial(White) && !b.HasMatingMaterial(Black)
 }
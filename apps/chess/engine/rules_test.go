@@ -0,0 +1,50 @@
+package engine
+
+import "testing"
+
+// playSAN plays the moves on b, failing the test on the first illegal one
+func playSAN(t *testing.T, b *Board, moves ...string) {
+	t.Helper()
+	for _, san := range moves {
+		m, err := b.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("ParseSAN(%q): %v", san, err)
+		}
+		if !b.MakeMove(m) {
+			t.Fatalf("MakeMove(%s) refused", san)
+		}
+		if b.Hash != b.BoardHash() {
+			t.Fatalf("after %s: incremental hash %x, rebuilt %x", san, b.Hash, b.BoardHash())
+		}
+	}
+}
+
+func TestRepetitionAfterDoublePush(t *testing.T) {
+	// The position after 1.e4 recurs after 3.Ng1 and 5.Ng1. Its en-passant
+	// square after 1.e4 cannot be taken, so it is the same position.
+	b := NewBoard()
+	playSAN(t, b, "e4", "Nf6", "Nf3", "Ng8", "Ng1", "Nf6", "Nf3", "Ng8", "Ng1")
+
+	if got := b.RepetitionCount(); got != 3 {
+		t.Errorf("RepetitionCount() = %d, want 3", got)
+	}
+	if !b.IsThreefoldRepetition() {
+		t.Error("IsThreefoldRepetition() = false, want true")
+	}
+
+	playSAN(t, b, "Nf6", "Nf3", "Ng8", "Ng1", "Nf6", "Nf3", "Ng8", "Ng1")
+	if !b.IsFivefoldRepetition() {
+		t.Errorf("IsFivefoldRepetition() = false after %d occurrences, want true", b.RepetitionCount())
+	}
+}
+
+func TestRepetitionCapturableEnPassant(t *testing.T) {
+	// After 3...d5 white may take en passant; once the knights have danced
+	// that right is gone, so the position does not repeat
+	b := NewBoard()
+	playSAN(t, b, "e4", "Nf6", "e5", "Ng8", "Nf3", "d5", "Ng1", "Nf6", "Nf3", "Ng8", "Ng1", "Nf6", "Nf3", "Ng8")
+
+	if got := b.RepetitionCount(); got != 2 {
+		t.Errorf("RepetitionCount() = %d, want 2", got)
+	}
+}
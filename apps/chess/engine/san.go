@@ -0,0 +1,291 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSAN is wrapped by every error returned from ParseSAN
+var ErrInvalidSAN = errors.New("invalid SAN")
+
+// SAN piece letters (pawns have none)
+var pieceToSAN = [PieceNB]string{
+	Pawn:   "",
+	Knight: "N",
+	Bishop: "B",
+	Rook:   "R",
+	Queen:  "Q",
+	King:   "K",
+}
+
+// --------------------------
+// Legal moves
+// --------------------------
+
+// LegalMoves returns all legal moves for the side to move
+func (b *Board) LegalMoves() []Move {
+	pseudo := b.GeneratePseudoLegalMoves()
+	legal := make([]Move, 0, len(pseudo))
+	for _, m := range pseudo {
+		if b.TryMove(m) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+// --------------------------
+// SAN generation
+// --------------------------
+
+// SAN returns the Standard Algebraic Notation of a legal move in the
+// current position (e4, Nbd7, exd6, O-O, e8=Q+, Qh4#)
+func (b *Board) SAN(m Move) string {
+	_, piece, _ := b.PieceAt(m.From)
+
+	var san strings.Builder
+
+	if piece == King && (m.IsCastle() || m.To == m.From+2 || m.To+2 == m.From) {
+		if m.To%8 == 6 {
+			san.WriteString("O-O")
+		} else {
+			san.WriteString("O-O-O")
+		}
+	} else {
+		capture := b.All&bit(m.To) != 0 || (piece == Pawn && m.From%8 != m.To%8)
+
+		if piece == Pawn {
+			if capture {
+				san.WriteByte('a' + m.From%8)
+			}
+		} else {
+			san.WriteString(pieceToSAN[piece])
+			san.WriteString(b.disambiguation(m, piece))
+		}
+
+		if capture {
+			san.WriteByte('x')
+		}
+		san.WriteString(squareName(m.To))
+
+		if m.Promotion != NoPiece {
+			san.WriteByte('=')
+			san.WriteString(pieceToSAN[m.Promotion])
+		}
+	}
+
+	san.WriteString(b.checkSuffix(m))
+	return san.String()
+}
+
+// disambiguation returns the from-file, from-rank or full from-square
+// needed when another piece of the same type can reach the same square
+func (b *Board) disambiguation(m Move, piece Piece) string {
+	ambiguous, sameFile, sameRank := false, false, false
+
+	for _, other := range b.LegalMoves() {
+		if other.To != m.To || other.From == m.From {
+			continue
+		}
+		if _, p, _ := b.PieceAt(other.From); p != piece {
+			continue
+		}
+		ambiguous = true
+		if other.From%8 == m.From%8 {
+			sameFile = true
+		}
+		if other.From/8 == m.From/8 {
+			sameRank = true
+		}
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return string(rune('a' + m.From%8))
+	case !sameRank:
+		return string(rune('1' + m.From/8))
+	default:
+		return squareName(m.From)
+	}
+}
+
+// checkSuffix returns "+" for check, "#" for checkmate, "" otherwise
+func (b *Board) checkSuffix(m Move) string {
+	if !b.MakeMove(m) {
+		return ""
+	}
+	defer b.UnapplyMove()
+
+	if b.IsVariantLoss() {
+		return "#"
+	}
+	if !b.IsKingInCheck(b.SideToMove) {
+		return ""
+	}
+	if !b.HasLegalMoves(b.SideToMove) {
+		return "#"
+	}
+	return "+"
+}
+
+// --------------------------
+// SAN parsing
+// --------------------------
+
+// ParseSAN resolves a SAN string against the legal moves of the position.
+// Check/mate suffixes and annotation glyphs (!, ?) are ignored, and common
+// variants are accepted: 0-0 castling, "e8Q" promotions and "e.p." marks.
+func (b *Board) ParseSAN(s string) (Move, error) {
+	orig := s
+	s = strings.TrimRight(s, "+#!?")
+	s = strings.TrimSuffix(s, "e.p.")
+	s = strings.ReplaceAll(s, "0", "O")
+
+	legal := b.LegalMoves()
+
+	// Castling
+	if s == "O-O" || s == "O-O-O" {
+		for _, m := range legal {
+			if !m.IsCastle() {
+				continue
+			}
+			if (s == "O-O") == (m.To%8 == 6) {
+				return m, nil
+			}
+		}
+		return Move{}, sanError(orig, "castling is not legal")
+	}
+
+	// Promotion
+	promo := NoPiece
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		if i+2 != len(s) {
+			return Move{}, sanError(orig, "malformed promotion")
+		}
+		promo = sanPiece(s[i+1])
+		if promo == NoPiece || promo == Pawn || promo == King {
+			return Move{}, sanError(orig, "invalid promotion piece")
+		}
+		s = s[:i]
+	} else if n := len(s); n >= 3 && s[n-2] >= '1' && s[n-2] <= '8' {
+		// Promotion without '=' (e8Q)
+		if p := sanPiece(s[n-1]); p != NoPiece {
+			if p == Pawn || p == King {
+				return Move{}, sanError(orig, "invalid promotion piece")
+			}
+			promo = p
+			s = s[:n-1]
+		}
+	}
+
+	// Destination square
+	if len(s) < 2 {
+		return Move{}, sanError(orig, "too short")
+	}
+	to, ok := parseSquare(s[len(s)-2:])
+	if !ok {
+		return Move{}, sanError(orig, "invalid destination square")
+	}
+	s = s[:len(s)-2]
+
+	// Piece letter, then optional from-file / from-rank / capture mark
+	piece := Pawn
+	if s != "" && s[0] >= 'A' && s[0] <= 'Z' {
+		piece = sanPiece(s[0])
+		if piece == NoPiece || piece == Pawn {
+			return Move{}, sanError(orig, "invalid piece letter")
+		}
+		s = s[1:]
+	}
+	s = strings.TrimSuffix(s, "x")
+
+	fromFile, fromRank := -1, -1
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch >= 'a' && ch <= 'h' && fromFile < 0:
+			fromFile = int(ch - 'a')
+		case ch >= '1' && ch <= '8' && fromRank < 0:
+			fromRank = int(ch - '1')
+		default:
+			return Move{}, sanError(orig, "unexpected character %q", ch)
+		}
+	}
+
+	var found []Move
+	for _, m := range legal {
+		if m.To != to || m.Promotion != promo {
+			continue
+		}
+		if _, p, _ := b.PieceAt(m.From); p != piece {
+			continue
+		}
+		if fromFile >= 0 && int(m.From%8) != fromFile {
+			continue
+		}
+		if fromRank >= 0 && int(m.From/8) != fromRank {
+			continue
+		}
+		found = append(found, m)
+	}
+
+	switch len(found) {
+	case 0:
+		return Move{}, sanError(orig, "no legal move matches")
+	case 1:
+		return found[0], nil
+	default:
+		return Move{}, sanError(orig, "ambiguous move")
+	}
+}
+
+// --------------------------
+// Move history
+// --------------------------
+
+// SANHistory returns the moves on the MoveStack in SAN, replayed from the
+// position before the first stacked move
+func (b *Board) SANHistory() []string {
+	replay := b.Clone()
+	for len(replay.MoveStack) > 0 {
+		replay.UnapplyMove()
+	}
+
+	sans := make([]string, 0, len(b.MoveStack))
+	for _, ms := range b.MoveStack {
+		m := Move{From: ms.From, To: ms.To, Promotion: ms.Promotion, Flags: ms.Flags}
+		sans = append(sans, replay.SAN(m))
+		replay.MakeMove(m)
+	}
+	return sans
+}
+
+// sanPiece converts an upper-case SAN letter to a piece
+func sanPiece(ch byte) Piece {
+	switch ch {
+	case 'P':
+		return Pawn
+	case 'N':
+		return Knight
+	case 'B':
+		return Bishop
+	case 'R':
+		return Rook
+	case 'Q':
+		return Queen
+	case 'K':
+		return King
+	}
+	return NoPiece
+}
+
+// squareName converts a square index to algebraic notation ("e4")
+func squareName(sq uint8) string {
+	return string([]byte{'a' + sq%8, '1' + sq/8})
+}
+
+func sanError(s string, format string, args ...any) error {
+	return fmt.Errorf("%w %q: %s", ErrInvalidSAN, s, fmt.Sprintf(format, args...))
+}
@@ -2,7 +2,7 @@ package engine
 
 import (
 	"context"
-	"math/bits"
+	"sync"
 	"time"
 )
 
@@ -20,7 +20,15 @@ type SearchResult struct {
 	BestMove Move
 	Score    int
 	Depth    int
+	SelDepth int // deepest ply reached, quiescence included
 	Nodes    uint64
+	Time     time.Duration
+	NPS      uint64
+	Mate     int      // moves to mate, negative when being mated, 0 if none
+	PV       []Move   // principal variation, starting with BestMove
+	Lines    []PVLine // best MultiPV root lines, best first
+	Book     bool     // BestMove came from the opening book; nothing was searched
+	TBHit    bool     // BestMove came from the tablebases; nothing was searched
 }
 
 // --------------------
@@ -33,13 +41,35 @@ type Searcher struct {
 	Ctx      context.Context
 	MaxDepth int
 
-	moveBuf [][]Move // preallocated per-ply moves
-	killer  [][]Move // killer moves per-ply
-	history [ColorNB][PieceNB][64]int
+	// Threads is the number of search threads sharing TT (Lazy SMP).
+	// Below 2 the search runs on the calling goroutine only, so results are
+	// deterministic.
+	Threads int
+
+	// MultiPV is the number of root lines to search and report (min 1)
+	MultiPV int
+
+	// Book, if set, is probed before searching; a hit is played at once
+	Book *Book
+
+	// TB, if set, picks the move at a root it covers, and gives exact
+	// results in the tree once few enough pieces remain
+	TB *Tablebase
+
+	// OnInfo, if set, is called on the search goroutine with one record per
+	// MultiPV line after every completed iteration
+	OnInfo func(SearchInfo)
+
+	moveBuf  [][]Move // preallocated per-ply moves
+	killer   [][]Move // killer moves per-ply
+	history  [ColorNB][PieceNB][64]int
+	pv       [][]Move // triangular PV table: pv[ply] is the line from ply
+	selDepth int
+	start    time.Time
 }
 
 // --------------------
-// Evaluation
+// Move ordering values
 // --------------------
 
 var pieceValue = [PieceNB]int{
@@ -51,55 +81,167 @@ var pieceValue = [PieceNB]int{
 	King:   0,
 }
 
-func Evaluate(b *Board) int {
-	score := 0
-	for color := Color(0); color < ColorNB; color++ {
-		sign := 1
-		if color != b.SideToMove {
-			sign = -1
-		}
-		for p := Piece(0); p < PieceNB; p++ {
-			score += sign * pieceValue[p] * bits.OnesCount64(b.Pieces[color][p])
-		}
-	}
-	return score
-}
-
 // --------------------
 // Public entry point
 // --------------------
 
 func (s *Searcher) Search(b *Board, maxDepth int, timeLimit time.Duration) SearchResult {
+	return s.SearchContext(context.Background(), b, maxDepth, timeLimit)
+}
+
+// SearchContext is Search with a parent context, so callers can stop the
+// search early (UCI "stop", a position change, ...). The result of the
+// last completed iteration is returned.
+func (s *Searcher) SearchContext(parent context.Context, b *Board, maxDepth int, timeLimit time.Duration) SearchResult {
+	if s.Book != nil {
+		if m, ok := s.Book.Probe(b); ok {
+			return SearchResult{BestMove: m, PV: []Move{m}, Book: true}
+		}
+	}
+	if res, ok := s.probeRoot(b); ok {
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(parent, timeLimit)
+	defer cancel()
+
+	s.start = time.Now()
+	s.TT.NewSearch()
+
+	helpers, stopHelpers := s.startHelpers(ctx, b, maxDepth)
+	res := s.iterate(ctx, b, maxDepth, 1)
+	stopHelpers()
+
+	for _, h := range helpers {
+		res.Nodes += h.Nodes
+	}
+	res.Time = time.Since(s.start)
+	res.NPS = nps(res.Nodes, res.Time)
+	return res
+}
+
+// probeRoot plays the tablebase move when the root is covered by the
+// tables, reporting it as a single one-move line
+func (s *Searcher) probeRoot(b *Board) (SearchResult, bool) {
+	if s.TB == nil {
+		return SearchResult{}, false
+	}
+	m, wdl, err := s.TB.ProbeRoot(b)
+	if err != nil {
+		return SearchResult{}, false
+	}
+
+	s.start = time.Now()
+	score := tbScore(wdl)
+	res := SearchResult{
+		BestMove: m,
+		Score:    score,
+		PV:       []Move{m},
+		Lines:    []PVLine{{Score: score, PV: []Move{m}}},
+		TBHit:    true,
+	}
+	s.report(res)
+	res.Time = time.Since(s.start)
+	return res, true
+}
+
+// iterate runs iterative deepening from startDepth until maxDepth or ctx
+// is done
+func (s *Searcher) iterate(ctx context.Context, b *Board, maxDepth, startDepth int) SearchResult {
 	s.Nodes = 0
 	s.MaxDepth = maxDepth
 	s.moveBuf = make([][]Move, maxDepth+2)
 	s.killer = make([][]Move, maxDepth+2)
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeLimit)
-	defer cancel()
+	s.pv = make([][]Move, maxDepth+2)
 	s.Ctx = ctx
 
-	s.TT.NewSearch()
+	res := SearchResult{Score: -INF}
 
-	var bestMove Move
-	bestScore := -INF
-	lastDepth := 0
-
-	for depth := 1; depth <= maxDepth; depth++ {
-		score, move := s.searchRoot(b, depth)
-		if ctx.Err() != nil {
+	for depth := startDepth; depth <= maxDepth; depth++ {
+		s.selDepth = 0
+		lines := s.searchRoot(b, depth)
+		if ctx.Err() != nil || len(lines) == 0 {
 			break
 		}
-		bestScore = score
-		bestMove = move
-		lastDepth = depth
+
+		for i := range lines {
+			lines[i].PV = s.extendPV(b, lines[i].PV, depth)
+		}
+
+		best := lines[0]
+		res = SearchResult{
+			BestMove: best.PV[0],
+			Score:    best.Score,
+			Depth:    depth,
+			SelDepth: s.selDepth,
+			Nodes:    s.Nodes,
+			Mate:     MateIn(best.Score),
+			PV:       best.PV,
+			Lines:    lines,
+		}
+		s.report(res)
 	}
 
-	return SearchResult{
-		BestMove: bestMove,
-		Score:    bestScore,
-		Depth:    lastDepth,
-		Nodes:    s.Nodes,
+	return res
+}
+
+// report sends one info record per root line to OnInfo
+func (s *Searcher) report(res SearchResult) {
+	if s.OnInfo == nil {
+		return
+	}
+
+	elapsed := time.Since(s.start)
+	for i, line := range res.Lines {
+		s.OnInfo(SearchInfo{
+			Depth:    res.Depth,
+			SelDepth: res.SelDepth,
+			MultiPV:  i + 1,
+			Score:    line.Score,
+			Mate:     MateIn(line.Score),
+			Nodes:    res.Nodes,
+			NPS:      nps(res.Nodes, elapsed),
+			Time:     elapsed,
+			Hashfull: s.TT.Hashfull(),
+			PV:       line.PV,
+		})
+	}
+}
+
+// --------------------
+// Lazy SMP
+// --------------------
+
+// startHelpers launches Threads-1 helper searchers on copies of the board.
+// They share only the transposition table: their results are discarded,
+// but the entries they store steer the main thread. Every other helper
+// starts one ply deeper so the threads do not search in lockstep. The
+// returned function stops the helpers and waits for them to exit.
+func (s *Searcher) startHelpers(ctx context.Context, b *Board, maxDepth int) ([]*Searcher, func()) {
+	if s.Threads < 2 {
+		return nil, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+
+	helpers := make([]*Searcher, s.Threads-1)
+	for i := range helpers {
+		h := &Searcher{TT: s.TT, TB: s.TB}
+		helpers[i] = h
+
+		board := b.Clone()
+		startDepth := 1 + (i+1)%2
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.iterate(ctx, board, maxDepth, min(startDepth, maxDepth))
+		}()
+	}
+
+	return helpers, func() {
+		cancel()
+		wg.Wait()
 	}
 }
 
@@ -107,13 +249,14 @@ func (s *Searcher) Search(b *Board, maxDepth int, timeLimit time.Duration) Searc
 // Root search
 // --------------------
 
-func (s *Searcher) searchRoot(b *Board, depth int) (int, Move) {
-	alpha := -INF
-	beta := INF
+// searchRoot returns the best MultiPV root lines, best first. A move only
+// needs to beat the worst line kept so far, so with MultiPV 1 this is a
+// plain alpha-beta root.
+func (s *Searcher) searchRoot(b *Board, depth int) []PVLine {
+	want := max(s.MultiPV, 1)
+	var lines []PVLine
 
 	moves := b.GeneratePseudoLegalMoves()
-	bestMove := Move{}
-	bestScore := -INF
 
 	// TT move first
 	if ttMove, ok := s.TT.GetMove(b.Hash); ok {
@@ -125,19 +268,40 @@ func (s *Searcher) searchRoot(b *Board, depth int) (int, Move) {
 			continue
 		}
 
-		score := -s.alphaBeta(b, depth-1, -beta, -alpha, 1, true)
+		alpha := -INF
+		if len(lines) == want {
+			alpha = lines[want-1].Score
+		}
+
+		s.pv[1] = s.pv[1][:0]
+		score := -s.alphaBeta(b, depth-1, -INF, -alpha, 1, true)
 		b.UnapplyMove()
 
-		if score > bestScore {
-			bestScore = score
-			bestMove = m
+		if s.Ctx.Err() != nil {
+			return nil
 		}
-		if score > alpha {
-			alpha = score
+		if len(lines) == want && score <= alpha {
+			continue
 		}
+
+		line := PVLine{Score: score, PV: append([]Move{m}, s.pv[1]...)}
+		lines = insertLine(lines, line, want)
 	}
 
-	return bestScore, bestMove
+	return lines
+}
+
+// insertLine adds line in score order, keeping at most n lines
+func insertLine(lines []PVLine, line PVLine, n int) []PVLine {
+	i := len(lines)
+	for i > 0 && lines[i-1].Score < line.Score {
+		i--
+	}
+	lines = append(lines[:i], append([]PVLine{line}, lines[i:]...)...)
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return lines
 }
 
 // --------------------
@@ -149,25 +313,51 @@ func (s *Searcher) alphaBeta(b *Board, depth, alpha, beta, ply int, allowNull bo
 		return 0
 	}
 	s.Nodes++
+	s.selDepth = max(s.selDepth, ply)
+	s.pv[ply] = s.pv[ply][:0]
+
+	// Lost by the variant's rules: scored like being mated
+	if b.IsVariantLoss() {
+		return -MATE_SCORE + ply
+	}
+
+	// Repetition / fifty-move draw
+	if b.IsFiftyMoveRule() || b.IsRepetition() {
+		return 0
+	}
 
 	// Transposition Table
-	if val, _, ok := s.TT.Probe(b.Hash, depth, alpha, beta); ok {
+	if val, _, ok := s.TT.Probe(b.Hash, depth, alpha, beta, ply); ok {
 		return val
 	}
 
+	// Tablebases, right after a capture or pawn move: the fifty-move count
+	// is then zero, as the tables assume
+	if s.TB != nil && b.HalfMoveClock == 0 && s.TB.CanProbe(b) {
+		if wdl, err := s.TB.ProbeWDL(b); err == nil {
+			score := tbScore(wdl)
+			s.TT.Store(b.Hash, s.MaxDepth, score, TTExact, Move{}, ply)
+			return score
+		}
+	}
+
 	if depth <= 0 {
-		return s.quiescence(b, alpha, beta)
+		return s.quiescence(b, alpha, beta, ply)
 	}
 
 	// Null-move pruning
 	if allowNull && depth > NULLMOVE_REDUCTION+1 && !b.IsKingInCheck(b.SideToMove) {
 		prevHash := b.Hash
 		prevSide := b.SideToMove
+		prevEP := b.EnPassant
+		// The en-passant right lapses with the passed move
+		b.Hash ^= ZSide ^ b.epKey(prevEP, prevSide)
 		b.SideToMove ^= 1
-		b.Hash ^= ZSide // flip side hash
+		b.EnPassant = NoSquare
 		score := -s.alphaBeta(b, depth-1-NULLMOVE_REDUCTION, -beta, -beta+1, ply+1, false)
 		b.SideToMove = prevSide
 		b.Hash = prevHash
+		b.EnPassant = prevEP
 		if score >= beta {
 			return beta
 		}
@@ -221,6 +411,7 @@ func (s *Searcher) alphaBeta(b *Board, depth, alpha, beta, ply int, allowNull bo
 		if score > alpha {
 			alpha = score
 			bestMove = m
+			s.pv[ply] = append(append(s.pv[ply][:0], m), s.pv[ply+1]...)
 			if m.Flags&MoveCapture == 0 {
 				s.history[color][piece][m.To] += depth * depth
 			}
@@ -248,11 +439,16 @@ func (s *Searcher) alphaBeta(b *Board, depth, alpha, beta, ply int, allowNull bo
 // Quiescence search
 // --------------------
 
-func (s *Searcher) quiescence(b *Board, alpha, beta int) int {
+func (s *Searcher) quiescence(b *Board, alpha, beta, ply int) int {
 	if s.Nodes&4095 == 0 && s.Ctx.Err() != nil {
 		return 0
 	}
 	s.Nodes++
+	s.selDepth = max(s.selDepth, ply)
+
+	if b.IsVariantLoss() {
+		return -MATE_SCORE + ply
+	}
 
 	score := Evaluate(b)
 	if score >= beta {
@@ -270,7 +466,7 @@ func (s *Searcher) quiescence(b *Board, alpha, beta int) int {
 			continue
 		}
 
-		score := -s.quiescence(b, -beta, -alpha)
+		score := -s.quiescence(b, -beta, -alpha, ply+1)
 		b.UnapplyMove()
 
 		if score >= beta {
@@ -336,13 +532,7 @@ func sortCapturesMVV(moves []Move, b *Board) []Move {
 	for i := 1; i < len(moves); i++ {
 		j := i
 		for j > 0 {
-			attacker := b.pieceOnSquare(moves[j].From)
-			victim := b.pieceOnSquare(moves[j].To)
-			score := pieceValue[victim]*10 - pieceValue[attacker]
-			prevAttacker := b.pieceOnSquare(moves[j-1].From)
-			prevVictim := b.pieceOnSquare(moves[j-1].To)
-			prevScore := pieceValue[prevVictim]*10 - pieceValue[prevAttacker]
-			if score > prevScore {
+			if mvvLVA(b, moves[j]) > mvvLVA(b, moves[j-1]) {
 				moves[j], moves[j-1] = moves[j-1], moves[j]
 				j--
 			} else {
@@ -353,6 +543,15 @@ func sortCapturesMVV(moves []Move, b *Board) []Move {
 	return moves
 }
 
+func mvvLVA(b *Board, m Move) int {
+	attacker := b.pieceOnSquare(m.From)
+	victim := b.pieceOnSquare(m.To)
+	if victim == NoPiece {
+		victim = Pawn // en passant: the target square is empty
+	}
+	return pieceValue[victim]*10 - pieceValue[attacker]
+}
+
 // --------------------
 // Move ordering helper
 // --------------------
@@ -0,0 +1,951 @@
+package engine
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// --------------------
+// Syzygy tablebases
+// --------------------
+
+// The reader follows the layout of the reference probing code: each table
+// file holds, per side to move and (for pawn tables) per leading-pawn file,
+// a Huffman-compressed stream of values indexed by a canonical encoding of
+// the position.
+
+// tbMaxPieces is the largest table size the index encoding supports
+const tbMaxPieces = 7
+
+var (
+	tbWDLMagic = [4]byte{0x71, 0xE8, 0x23, 0x5D}
+	tbDTZMagic = [4]byte{0xD7, 0x66, 0x0C, 0xA5}
+)
+
+// Table flags (per PairsData)
+const (
+	tbFlagSTM         = 1
+	tbFlagMapped      = 2
+	tbFlagWinPlies    = 4
+	tbFlagLossPlies   = 8
+	tbFlagWide        = 16
+	tbFlagSingleValue = 128
+)
+
+// WDL is a tablebase result from the side to move's point of view
+type WDL int
+
+const (
+	WDLLoss        WDL = -2
+	WDLBlessedLoss WDL = -1 // lost, but drawn by the fifty-move rule
+	WDLDraw        WDL = 0
+	WDLCursedWin   WDL = 1 // won, but drawn by the fifty-move rule
+	WDLWin         WDL = 2
+)
+
+var (
+	ErrTBMissing = errors.New("syzygy: table not available")
+	ErrTBNoMoves = errors.New("syzygy: no legal moves")
+)
+
+// tbNamePattern matches table file names such as KRPvKR.rtbw
+var tbNamePattern = regexp.MustCompile(`^(K[QRBNP]*)v(K[QRBNP]*)\.rtbw$`)
+
+// --------------------
+// Tablebase
+// --------------------
+
+// Tablebase is a set of Syzygy tables found on disk. Files are mapped on
+// first use; it is safe for concurrent probing.
+type Tablebase struct {
+	MaxPieces int
+
+	tables map[uint64]*tbTable // by material key, both color orientations
+}
+
+// LoadTablebase indexes the .rtbw (and matching .rtbz) files in the given
+// directories, separated like PATH entries
+func LoadTablebase(paths string) (*Tablebase, error) {
+	tb := &Tablebase{tables: make(map[uint64]*tbTable)}
+
+	for _, dir := range filepath.SplitList(paths) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, de := range entries {
+			m := tbNamePattern.FindStringSubmatch(de.Name())
+			if m == nil {
+				continue
+			}
+			t := newTBTable(m[1], m[2])
+			if t.pieceCount > tbMaxPieces {
+				continue
+			}
+			if _, ok := tb.tables[t.key]; ok {
+				continue // the same table in an earlier directory
+			}
+
+			t.wdl.path = filepath.Join(dir, de.Name())
+			dtz := strings.TrimSuffix(t.wdl.path, ".rtbw") + ".rtbz"
+			if _, err := os.Stat(dtz); err == nil {
+				t.dtz.path = dtz
+			}
+
+			tb.tables[t.key] = t
+			tb.tables[t.key2] = t
+			tb.MaxPieces = max(tb.MaxPieces, t.pieceCount)
+		}
+	}
+
+	if len(tb.tables) == 0 {
+		return nil, fmt.Errorf("syzygy: no tables found in %q", paths)
+	}
+	return tb, nil
+}
+
+// Len returns the number of tables found
+func (tb *Tablebase) Len() int {
+	seen := map[*tbTable]bool{}
+	for _, t := range tb.tables {
+		seen[t] = true
+	}
+	return len(seen)
+}
+
+// --------------------
+// Material keys
+// --------------------
+
+// materialKey packs the piece counts per color and type, 4 bits each
+func materialKey(counts *[ColorNB][PieceNB]int) uint64 {
+	var key uint64
+	for c := 0; c < ColorNB; c++ {
+		for p := 0; p < PieceNB; p++ {
+			key |= uint64(counts[c][p]) << (4 * (c*PieceNB + p))
+		}
+	}
+	return key
+}
+
+func boardMaterialKey(b *Board) uint64 {
+	var counts [ColorNB][PieceNB]int
+	for c := 0; c < ColorNB; c++ {
+		for p := 0; p < PieceNB; p++ {
+			counts[c][p] = bits.OnesCount64(b.Pieces[c][p])
+		}
+	}
+	return materialKey(&counts)
+}
+
+// --------------------
+// Tables
+// --------------------
+
+type tbTable struct {
+	name            string // file name without extension, strong side first
+	key, key2       uint64 // material with the first side as white / as black
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	pawnCount       [2]int // leading color first
+
+	wdl tbFile
+	dtz tbFile
+}
+
+// tbFile is one mapped .rtbw or .rtbz file
+type tbFile struct {
+	path string
+	once sync.Once
+	err  error
+	data []byte
+
+	pairs  [2][4]*pairsData // [side][leading pawn file]
+	dtzMap int              // offset of the DTZ value maps
+}
+
+func newTBTable(white, black string) *tbTable {
+	t := &tbTable{name: white + "v" + black}
+
+	var counts [ColorNB][PieceNB]int
+	for c, side := range []string{white, black} {
+		for _, ch := range side {
+			p := Piece(strings.IndexRune("PNBRQK", ch))
+			counts[c][p]++
+			t.pieceCount++
+		}
+	}
+	t.key = materialKey(&counts)
+	counts[White], counts[Black] = counts[Black], counts[White]
+	t.key2 = materialKey(&counts)
+	counts[White], counts[Black] = counts[Black], counts[White]
+
+	for c := 0; c < ColorNB; c++ {
+		for p := Pawn; p < King; p++ {
+			if counts[c][p] == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+
+	wp, bp := counts[White][Pawn], counts[Black][Pawn]
+	t.hasPawns = wp+bp > 0
+
+	// The leading color is the side with fewer pawns (white on a tie), or
+	// the only side with pawns
+	if bp == 0 || (wp > 0 && bp >= wp) {
+		t.pawnCount = [2]int{wp, bp}
+	} else {
+		t.pawnCount = [2]int{bp, wp}
+	}
+	return t
+}
+
+// open maps f and parses its headers once
+func (t *tbTable) open(f *tbFile, dtz bool) error {
+	f.once.Do(func() {
+		if f.path == "" {
+			f.err = ErrTBMissing
+			return
+		}
+		data, err := mapFile(f.path)
+		if err != nil {
+			f.err = err
+			return
+		}
+
+		magic := tbWDLMagic
+		if dtz {
+			magic = tbDTZMagic
+		}
+		if len(data) < 16 || len(data)%64 != 16 || [4]byte(data[:4]) != magic {
+			f.err = fmt.Errorf("syzygy: %s is corrupt", f.path)
+			return
+		}
+		f.data = data
+
+		defer func() {
+			// A truncated file shows up as an out-of-range read
+			if r := recover(); r != nil {
+				f.err = fmt.Errorf("syzygy: %s is corrupt: %v", f.path, r)
+			}
+		}()
+		t.parse(f, dtz)
+	})
+	return f.err
+}
+
+// parse reads the per-table headers that follow the magic
+func (t *tbTable) parse(f *tbFile, dtz bool) {
+	data := f.data
+	pos := 4
+	pos++ // flags: split, has pawns
+
+	sides := 1
+	if !dtz && t.key != t.key2 {
+		sides = 2
+	}
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	pp := t.hasPawns && t.pawnCount[1] > 0
+
+	for file := 0; file <= maxFile; file++ {
+		for i := 0; i < sides; i++ {
+			f.pairs[i][file] = &pairsData{}
+		}
+
+		order := [2][2]int{
+			{int(data[pos] & 0xF), 0xF},
+			{int(data[pos] >> 4), 0xF},
+		}
+		if pp {
+			order[0][1] = int(data[pos+1] & 0xF)
+			order[1][1] = int(data[pos+1] >> 4)
+			pos++
+		}
+		pos++
+
+		for k := 0; k < t.pieceCount; k, pos = k+1, pos+1 {
+			for i := 0; i < sides; i++ {
+				if i == 0 {
+					f.pairs[i][file].pieces[k] = data[pos] & 0xF
+				} else {
+					f.pairs[i][file].pieces[k] = data[pos] >> 4
+				}
+			}
+		}
+
+		for i := 0; i < sides; i++ {
+			t.setGroups(f.pairs[i][file], order[i], file)
+		}
+	}
+	pos += pos & 1
+
+	for file := 0; file <= maxFile; file++ {
+		for i := 0; i < sides; i++ {
+			pos = f.pairs[i][file].setSizes(data, pos)
+		}
+	}
+
+	if dtz {
+		f.dtzMap = pos
+		for file := 0; file <= maxFile; file++ {
+			d := f.pairs[0][file]
+			if d.flags&tbFlagMapped == 0 {
+				continue
+			}
+			if d.flags&tbFlagWide != 0 {
+				pos += pos & 1
+				for i := 0; i < 4; i++ {
+					d.mapIdx[i] = (pos-f.dtzMap)/2 + 1
+					pos += 2*int(binary.LittleEndian.Uint16(data[pos:])) + 2
+				}
+			} else {
+				for i := 0; i < 4; i++ {
+					d.mapIdx[i] = pos - f.dtzMap + 1
+					pos += int(data[pos]) + 1
+				}
+			}
+		}
+		pos += pos & 1
+	}
+
+	for file := 0; file <= maxFile; file++ {
+		for i := 0; i < sides; i++ {
+			d := f.pairs[i][file]
+			d.sparseIndex = pos
+			pos += d.sparseIndexSize * 6
+		}
+	}
+	for file := 0; file <= maxFile; file++ {
+		for i := 0; i < sides; i++ {
+			d := f.pairs[i][file]
+			d.blockLength = pos
+			pos += d.blockLengthSize * 2
+		}
+	}
+	for file := 0; file <= maxFile; file++ {
+		for i := 0; i < sides; i++ {
+			d := f.pairs[i][file]
+			pos = (pos + 0x3F) &^ 0x3F
+			d.blocks = pos
+			pos += d.numBlocks * d.blockSize
+		}
+	}
+
+	if pos > len(data) {
+		panic("tables extend past end of file")
+	}
+}
+
+// setGroups splits the piece sequence into groups of like pieces and
+// computes each group's index multiplier. The leading group (kings plus a
+// unique piece, or the leading pawns) is encoded specially.
+func (t *tbTable) setGroups(d *pairsData, order [2]int, file int) {
+	firstLen := 2
+	switch {
+	case t.hasPawns:
+		firstLen = 0
+	case t.hasUniquePieces:
+		firstLen = 3
+	}
+
+	n := 0
+	d.groupLen[0] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch {
+		case k == order[0]:
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= tbLeadPawnsSize[d.groupLen[0]][file]
+			case t.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case k == order[1]:
+			d.groupIdx[1] = idx
+			idx *= tbBinomial[d.groupLen[1]][48-d.groupLen[0]]
+		default:
+			d.groupIdx[next] = idx
+			idx *= tbBinomial[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// --------------------
+// Compressed data
+// --------------------
+
+// pairsData describes one compressed value stream. Offsets point into the
+// file's data.
+type pairsData struct {
+	flags           byte
+	blockSize       int
+	span            uint64
+	numBlocks       int
+	minSymLen       int
+	lowestSym       int
+	btree           int
+	blockLength     int
+	blockLengthSize int
+	sparseIndex     int
+	sparseIndexSize int
+	blocks          int
+	base64          []uint64
+	symLen          []uint8
+
+	pieces   [tbMaxPieces]byte
+	groupIdx [tbMaxPieces + 1]uint64
+	groupLen [tbMaxPieces + 1]int
+	mapIdx   [4]int // DTZ value maps for win, loss, cursed win, blessed loss
+}
+
+// setSizes reads the stream's header at pos and returns the offset past it
+func (d *pairsData) setSizes(data []byte, pos int) int {
+	d.flags = data[pos]
+	pos++
+
+	if d.flags&tbFlagSingleValue != 0 {
+		d.minSymLen = int(data[pos]) // the single value
+		return pos + 1
+	}
+
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	tbSize := d.groupIdx[n]
+
+	d.blockSize = 1 << data[pos]
+	d.span = 1 << data[pos+1]
+	d.sparseIndexSize = int((tbSize + d.span - 1) / d.span)
+	padding := int(data[pos+2])
+	d.numBlocks = int(binary.LittleEndian.Uint32(data[pos+3:]))
+	d.blockLengthSize = d.numBlocks + padding
+	maxSymLen := int(data[pos+7])
+	d.minSymLen = int(data[pos+8])
+	pos += 9
+
+	d.lowestSym = pos
+	d.base64 = make([]uint64, maxSymLen-d.minSymLen+1)
+
+	// Canonical Huffman: the base of each code length, left-aligned
+	for i := len(d.base64) - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(d.lowest(data, i)) - uint64(d.lowest(data, i+1))) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= 64 - i - d.minSymLen
+	}
+	pos += len(d.base64) * 2
+
+	d.symLen = make([]uint8, binary.LittleEndian.Uint16(data[pos:]))
+	pos += 2
+	d.btree = pos
+
+	visited := make([]bool, len(d.symLen))
+	for sym := range d.symLen {
+		if !visited[sym] {
+			d.symLen[sym] = d.setSymLen(data, sym, visited)
+		}
+	}
+	return pos + len(d.symLen)*3 + len(d.symLen)&1
+}
+
+func (d *pairsData) lowest(data []byte, i int) uint16 {
+	return binary.LittleEndian.Uint16(data[d.lowestSym+2*i:])
+}
+
+// pair returns the two symbols a symbol expands into ("Recursive Pairing");
+// right is 0xFFF for a leaf, whose value is then left
+func (d *pairsData) pair(data []byte, sym int) (left, right int) {
+	lr := data[d.btree+3*sym:]
+	left = int(lr[1]&0xF)<<8 | int(lr[0])
+	right = int(lr[2])<<4 | int(lr[1]>>4)
+	return left, right
+}
+
+// setSymLen counts the values (minus one) a symbol expands into
+func (d *pairsData) setSymLen(data []byte, sym int, visited []bool) uint8 {
+	visited[sym] = true
+	left, right := d.pair(data, sym)
+	if right == 0xFFF {
+		return 0
+	}
+	if !visited[left] {
+		d.symLen[left] = d.setSymLen(data, left, visited)
+	}
+	if !visited[right] {
+		d.symLen[right] = d.setSymLen(data, right, visited)
+	}
+	return d.symLen[left] + d.symLen[right] + 1
+}
+
+// decompress returns the value stored at idx
+func (d *pairsData) decompress(data []byte, idx uint64) int {
+	if d.flags&tbFlagSingleValue != 0 {
+		return d.minSymLen
+	}
+
+	// The sparse index gives a block and offset near idx; walk from there
+	k := idx / d.span
+	entry := data[d.sparseIndex+6*int(k):]
+	block := int(binary.LittleEndian.Uint32(entry))
+	offset := int(binary.LittleEndian.Uint16(entry[4:]))
+	offset += int(idx%d.span) - int(d.span/2)
+
+	blockLen := func(b int) int {
+		return int(binary.LittleEndian.Uint16(data[d.blockLength+2*b:]))
+	}
+	for offset < 0 {
+		block--
+		offset += blockLen(block) + 1
+	}
+	for offset > blockLen(block) {
+		offset -= blockLen(block) + 1
+		block++
+	}
+
+	// Walk the block's symbols until the one covering offset
+	ptr := d.blocks + block*d.blockSize
+	buf64 := binary.BigEndian.Uint64(data[ptr:])
+	ptr += 8
+	buf64Size := 64
+
+	var sym int
+	for {
+		l := 0
+		for buf64 < d.base64[l] {
+			l++
+		}
+		sym = int((buf64-d.base64[l])>>(64-l-d.minSymLen)) + int(d.lowest(data, l))
+
+		if offset < int(d.symLen[sym])+1 {
+			break
+		}
+		offset -= int(d.symLen[sym]) + 1
+		l += d.minSymLen
+		buf64 <<= l
+		buf64Size -= l
+
+		if buf64Size <= 32 {
+			buf64Size += 32
+			buf64 |= uint64(binary.BigEndian.Uint32(data[ptr:])) << (64 - buf64Size)
+			ptr += 4
+		}
+	}
+
+	// Descend the pair tree to the value at offset
+	for d.symLen[sym] != 0 {
+		left, right := d.pair(data, sym)
+		if offset < int(d.symLen[left])+1 {
+			sym = left
+		} else {
+			offset -= int(d.symLen[left]) + 1
+			sym = right
+		}
+	}
+	left, _ := d.pair(data, sym)
+	return left
+}
+
+// --------------------
+// Index tables
+// --------------------
+
+var (
+	tbBinomial      [6][64]uint64 // tbBinomial[k][n] = n choose k
+	tbMapPawns      [64]int
+	tbLeadPawnIdx   [6][64]uint64
+	tbLeadPawnsSize [6][4]uint64
+	tbMapB1H1H7     [64]int
+	tbMapA1D1D4     [64]int
+	tbMapKK         [10][64]int
+)
+
+// offA1H8 is positive above the a1-h8 diagonal, negative below
+func offA1H8(sq int) int {
+	return sq>>3 - sq&7
+}
+
+// squareDistance is the number of king moves between two squares
+func squareDistance(a, b int) int {
+	df, dr := a&7-b&7, a>>3-b>>3
+	return max(df, -df, dr, -dr)
+}
+
+func init() {
+	tbBinomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < 6 && k <= n; k++ {
+			if k > 0 {
+				tbBinomial[k][n] += tbBinomial[k-1][n-1]
+			}
+			if k < n {
+				tbBinomial[k][n] += tbBinomial[k][n-1]
+			}
+		}
+	}
+
+	code := 0
+	for sq := 0; sq < 64; sq++ {
+		if offA1H8(sq) < 0 {
+			tbMapB1H1H7[sq] = code
+			code++
+		}
+	}
+
+	// The a1-d1-d4 triangle: squares below the diagonal, then the diagonal
+	code = 0
+	var diagonal []int
+	for sq := 0; sq <= 27; sq++ {
+		switch {
+		case offA1H8(sq) < 0 && sq&7 <= 3:
+			tbMapA1D1D4[sq] = code
+			code++
+		case offA1H8(sq) == 0 && sq&7 <= 3:
+			diagonal = append(diagonal, sq)
+		}
+	}
+	for _, sq := range diagonal {
+		tbMapA1D1D4[sq] = code
+		code++
+	}
+
+	// The 462 legal placements of two kings with the first in the
+	// triangle; with the first on the diagonal the second is not above it
+	type kk struct{ idx, sq int }
+	var bothOnDiagonal []kk
+	code = 0
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 <= 27; s1++ {
+			if tbMapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) {
+				continue
+			}
+			for s2 := 0; s2 < 64; s2++ {
+				switch {
+				case squareDistance(s1, s2) <= 1: // adjacent or the same square
+				case offA1H8(s1) == 0 && offA1H8(s2) > 0:
+				case offA1H8(s1) == 0 && offA1H8(s2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, kk{idx, s2})
+				default:
+					tbMapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, p := range bothOnDiagonal {
+		tbMapKK[p.idx][p.sq] = code
+		code++
+	}
+
+	// Leading pawns: the lead pawn is the one nearest the edge, then the
+	// lowest rank; tables are split by its file (a-d after mirroring)
+	available := 47
+	for cnt := 1; cnt <= 5; cnt++ {
+		for file := 0; file < 4; file++ {
+			var idx uint64
+			for rank := 1; rank <= 6; rank++ {
+				sq := rank*8 + file
+				if cnt == 1 {
+					tbMapPawns[sq] = available
+					available--
+					tbMapPawns[sq^7] = available
+					available--
+				}
+				tbLeadPawnIdx[cnt][sq] = idx
+				idx += tbBinomial[cnt-1][tbMapPawns[sq]]
+			}
+			tbLeadPawnsSize[cnt][file] = idx
+		}
+	}
+}
+
+// --------------------
+// Table probing
+// --------------------
+
+// tbPieceCode is the table file's piece numbering: 1-6 for white pawn to
+// king, 9-14 for black
+func tbPieceCode(c Color, p Piece) byte {
+	return byte(p) + 1 + 8*byte(c)
+}
+
+// probeTable looks up b in the WDL table, or the DTZ table when dtz is
+// set (wdl then selects the value map). changeSTM reports a DTZ table
+// that only stores the other side to move.
+func (tb *Tablebase) probeTable(b *Board, dtz bool, wdl WDL) (value int, changeSTM bool, err error) {
+	if bits.OnesCount64(b.All) == 2 {
+		return 0, false, nil // KvK
+	}
+
+	t, ok := tb.tables[boardMaterialKey(b)]
+	if !ok {
+		return 0, false, ErrTBMissing
+	}
+	f := &t.wdl
+	if dtz {
+		f = &t.dtz
+	}
+	if err := t.open(f, dtz); err != nil {
+		return 0, false, err
+	}
+
+	// Tables store the stronger side as white. A symmetric table only
+	// stores white to move, so black to move is looked up color-flipped.
+	symmetricBlackToMove := t.key == t.key2 && b.SideToMove == Black
+	blackStronger := boardMaterialKey(b) != t.key
+	flip := symmetricBlackToMove || blackStronger
+
+	flipColor, flipSquares := byte(0), 0
+	if flip {
+		flipColor, flipSquares = 8, 56
+	}
+	stm := int(b.SideToMove)
+	if flip {
+		stm ^= 1
+	}
+
+	var squares [tbMaxPieces]int
+	var pieces [tbMaxPieces]byte
+	size, leadPawnsCnt := 0, 0
+	var leadPawns uint64
+	tbFile := 0
+
+	if t.hasPawns {
+		// The lead pawns' color is that of the first piece in the table
+		pc := f.pairs[0][0].pieces[0] ^ flipColor
+		bb := b.Pieces[pc>>3][Pawn]
+		leadPawns = bb
+		for bb != 0 {
+			squares[size] = int(PopLSB(&bb)) ^ flipSquares
+			size++
+		}
+		leadPawnsCnt = size
+
+		best := 0
+		for i := 1; i < leadPawnsCnt; i++ {
+			if tbMapPawns[squares[i]] > tbMapPawns[squares[best]] {
+				best = i
+			}
+		}
+		squares[0], squares[best] = squares[best], squares[0]
+
+		tbFile = squares[0] & 7
+		if tbFile > 3 {
+			tbFile = (squares[0] ^ 7) & 7
+		}
+	}
+
+	if dtz {
+		d := f.pairs[0][tbFile]
+		if int(d.flags&tbFlagSTM) != stm && !(t.key == t.key2 && !t.hasPawns) {
+			return 0, true, nil
+		}
+	}
+
+	bb := b.All ^ leadPawns
+	for bb != 0 {
+		sq := PopLSB(&bb)
+		c, p, _ := b.PieceAt(sq)
+		squares[size] = int(sq) ^ flipSquares
+		pieces[size] = tbPieceCode(c, p) ^ flipColor
+		size++
+	}
+
+	side := stm
+	if dtz || t.key == t.key2 {
+		side = 0
+	}
+	d := f.pairs[side][tbFile]
+
+	// Order the pieces as the table lists them
+	for i := leadPawnsCnt; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// Mirror so the lead piece is on files a-d
+	if squares[0]&7 > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] ^= 7
+		}
+	}
+
+	var idx uint64
+	if t.hasPawns {
+		idx = tbLeadPawnIdx[leadPawnsCnt][squares[0]]
+		rest := squares[1:leadPawnsCnt]
+		sort.SliceStable(rest, func(i, j int) bool { return tbMapPawns[rest[i]] < tbMapPawns[rest[j]] })
+		for i := 1; i < leadPawnsCnt; i++ {
+			idx += tbBinomial[i][tbMapPawns[squares[i]]]
+		}
+	} else {
+		idx = pieceLeadIndex(t, d, squares[:size])
+	}
+
+	// Remaining groups: combinations of squares not taken by earlier groups
+	idx *= d.groupIdx[0]
+	start := d.groupLen[0]
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[start : start+d.groupLen[next]]
+		sort.Ints(group)
+
+		var n uint64
+		for i, sq := range group {
+			adjust := 0
+			for _, prev := range squares[:start] {
+				if sq > prev {
+					adjust++
+				}
+			}
+			s := sq - adjust
+			if remainingPawns {
+				s -= 8
+			}
+			n += tbBinomial[i+1][s]
+		}
+
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		start += d.groupLen[next]
+	}
+
+	value = d.decompress(f.data, idx)
+	if !dtz {
+		return value - 2, false, nil
+	}
+	return t.mapDTZ(f, tbFile, value, wdl), false, nil
+}
+
+// pieceLeadIndex encodes the leading group of a pawnless table, after
+// mirroring the position into the a1-d1-d4 triangle. squares is modified.
+func pieceLeadIndex(t *tbTable, d *pairsData, squares []int) uint64 {
+	if squares[0]>>3 > 3 {
+		for i := range squares {
+			squares[i] ^= 56
+		}
+	}
+
+	// Flip along a1-h8 so the first off-diagonal piece is below it
+	for i := 0; i < d.groupLen[0]; i++ {
+		off := offA1H8(squares[i])
+		if off == 0 {
+			continue
+		}
+		if off > 0 {
+			for j := i; j < len(squares); j++ {
+				squares[j] = (squares[j]>>3 | squares[j]<<3) & 63
+			}
+		}
+		break
+	}
+
+	if !t.hasUniquePieces {
+		return uint64(tbMapKK[tbMapA1D1D4[squares[0]]][squares[1]])
+	}
+
+	s0, s1, s2 := squares[0], squares[1], squares[2]
+	adjust1 := 0
+	if s1 > s0 {
+		adjust1 = 1
+	}
+	adjust2 := 0
+	if s2 > s0 {
+		adjust2++
+	}
+	if s2 > s1 {
+		adjust2++
+	}
+
+	var idx int
+	switch {
+	case offA1H8(s0) != 0:
+		idx = (tbMapA1D1D4[s0]*63+(s1-adjust1))*62 + s2 - adjust2
+	case offA1H8(s1) != 0:
+		idx = (6*63+(s0>>3)*28+tbMapB1H1H7[s1])*62 + s2 - adjust2
+	case offA1H8(s2) != 0:
+		idx = 6*63*62 + 4*28*62 + (s0>>3)*7*28 + ((s1>>3)-adjust1)*28 + tbMapB1H1H7[s2]
+	default:
+		idx = 6*63*62 + 4*28*62 + 4*7*28 + (s0>>3)*7*6 + ((s1>>3)-adjust1)*6 + (s2 >> 3) - adjust2
+	}
+	return uint64(idx)
+}
+
+// mapDTZ converts a stored DTZ value to plies
+func (t *tbTable) mapDTZ(f *tbFile, file, value int, wdl WDL) int {
+	d := f.pairs[0][file]
+
+	if d.flags&tbFlagMapped != 0 {
+		// Value maps are stored win, loss, cursed win, blessed loss
+		var m int
+		switch wdl {
+		case WDLWin:
+			m = d.mapIdx[0]
+		case WDLLoss:
+			m = d.mapIdx[1]
+		case WDLCursedWin:
+			m = d.mapIdx[2]
+		case WDLBlessedLoss:
+			m = d.mapIdx[3]
+		}
+		if d.flags&tbFlagWide != 0 {
+			value = int(binary.LittleEndian.Uint16(f.data[f.dtzMap+2*(m+value):]))
+		} else {
+			value = int(f.data[f.dtzMap+m+value])
+		}
+	}
+
+	if (wdl == WDLWin && d.flags&tbFlagWinPlies == 0) ||
+		(wdl == WDLLoss && d.flags&tbFlagLossPlies == 0) ||
+		wdl == WDLCursedWin || wdl == WDLBlessedLoss {
+		value *= 2
+	}
+	return value + 1
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package engine
+
+import "os"
+
+// mapFile reads a table file into memory where mmap is unavailable
+func mapFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
@@ -0,0 +1,286 @@
+package engine
+
+import "math/bits"
+
+// --------------------
+// Tablebase scores
+// --------------------
+
+// TBWinScore is the search score of a tablebase win. It sits below the
+// mate scores, so a found mate is still preferred and reported as one.
+const TBWinScore = mateBound - 100
+
+// tbScore converts a WDL result to a search score. Wins and losses that
+// the fifty-move rule turns into draws score as draws.
+func tbScore(wdl WDL) int {
+	switch wdl {
+	case WDLWin:
+		return TBWinScore
+	case WDLLoss:
+		return -TBWinScore
+	}
+	return 0
+}
+
+// maxDTZ bounds the root move ranks
+const maxDTZ = 1 << 18
+
+// CanProbe reports whether b is covered by the tables: standard chess, its
+// material has a table and there are no castling rights (the tables assume
+// none)
+func (tb *Tablebase) CanProbe(b *Board) bool {
+	if b.Variant != VariantStandard || b.Castling != 0 || bits.OnesCount64(b.All) > tb.MaxPieces {
+		return false
+	}
+	_, ok := tb.tables[boardMaterialKey(b)]
+	return ok || bits.OnesCount64(b.All) == 2
+}
+
+// --------------------
+// WDL
+// --------------------
+
+// ProbeWDL returns the result of b with best play, from the side to move's
+// point of view, assuming the fifty-move counter is zero
+func (tb *Tablebase) ProbeWDL(b *Board) (WDL, error) {
+	if !tb.CanProbe(b) {
+		return WDLDraw, ErrTBMissing
+	}
+	wdl, _, err := tb.search(b, false)
+	return wdl, err
+}
+
+// search resolves the captures (and with zeroing, pawn moves) of b before
+// trusting the table: the generator stores "don't care" values where such
+// a move wins, and tables hold no en-passant positions at all. zeroingBest
+// reports that the best move is such a move, so the DTZ table cannot be
+// used for b.
+func (tb *Tablebase) search(b *Board, zeroing bool) (wdl WDL, zeroingBest bool, err error) {
+	best := WDLLoss
+	moves := b.LegalMoves()
+	searched := 0
+
+	for _, m := range moves {
+		if !tbCapture(b, m) && (!zeroing || b.pieceOnSquare(m.From) != Pawn) {
+			continue
+		}
+		searched++
+
+		b.MakeMove(m)
+		v, _, err := tb.search(b, false)
+		b.UnapplyMove()
+		if err != nil {
+			return WDLDraw, false, err
+		}
+
+		if -v > best {
+			best = -v
+			if best >= WDLWin {
+				return best, true, nil
+			}
+		}
+	}
+
+	// With every legal move searched the table value is not needed, and
+	// may be wrong (an en-passant position)
+	allSearched := searched > 0 && searched == len(moves)
+	value := best
+	if !allSearched {
+		v, _, err := tb.probeTable(b, false, WDLDraw)
+		if err != nil {
+			return WDLDraw, false, err
+		}
+		value = WDL(v)
+	}
+
+	if best >= value {
+		return best, best > WDLDraw || allSearched, nil
+	}
+	return value, false, nil
+}
+
+// tbCapture reports whether m takes a piece, en passant included
+func tbCapture(b *Board, m Move) bool {
+	if _, _, ok := b.PieceAt(m.To); ok {
+		return true
+	}
+	return b.pieceOnSquare(m.From) == Pawn && m.From%8 != m.To%8
+}
+
+// --------------------
+// DTZ
+// --------------------
+
+// ProbeDTZ returns the distance in plies to the next capture or pawn move
+// (zeroing the fifty-move counter) with best play: positive when the side
+// to move wins, negative when it loses, 0 for a draw. Values beyond ±100
+// are wins and losses the fifty-move rule turns into draws.
+func (tb *Tablebase) ProbeDTZ(b *Board) (int, error) {
+	if !tb.CanProbe(b) {
+		return 0, ErrTBMissing
+	}
+	return tb.probeDTZ(b)
+}
+
+func (tb *Tablebase) probeDTZ(b *Board) (int, error) {
+	wdl, zeroingBest, err := tb.search(b, true)
+	if err != nil || wdl == WDLDraw {
+		return 0, err // DTZ tables store no draws
+	}
+	if zeroingBest {
+		return dtzBeforeZeroing(wdl), nil
+	}
+
+	dtz, changeSTM, err := tb.probeTable(b, true, wdl)
+	if err != nil {
+		return 0, err
+	}
+	if !changeSTM {
+		if wdl == WDLCursedWin || wdl == WDLBlessedLoss {
+			dtz += 100
+		}
+		return dtz * wdlSign(int(wdl)), nil
+	}
+
+	// The table only stores the other side to move: take the best DTZ
+	// one ply on
+	minDTZ := 0xFFFF
+	for _, m := range b.LegalMoves() {
+		zeroing := tbCapture(b, m) || b.pieceOnSquare(m.From) == Pawn
+
+		b.MakeMove(m)
+		var dtz int
+		if zeroing {
+			// The DTZ of this move itself, signed by the result it leads to
+			var v WDL
+			v, _, err = tb.search(b, false)
+			dtz = -dtzBeforeZeroing(v)
+		} else {
+			dtz, err = tb.probeDTZ(b)
+			dtz = -dtz
+		}
+		if dtz == 1 && b.IsKingInCheck(b.SideToMove) && !b.HasLegalMoves(b.SideToMove) {
+			minDTZ = 1 // mate
+		}
+		b.UnapplyMove()
+		if err != nil {
+			return 0, err
+		}
+
+		if !zeroing {
+			dtz += wdlSign(dtz)
+		}
+		if dtz < minDTZ && wdlSign(dtz) == wdlSign(int(wdl)) {
+			minDTZ = dtz
+		}
+	}
+
+	if minDTZ == 0xFFFF {
+		return -1, nil // no legal moves: mated
+	}
+	return minDTZ, nil
+}
+
+// dtzBeforeZeroing is the DTZ of a position whose best move zeroes the
+// counter with result wdl
+func dtzBeforeZeroing(wdl WDL) int {
+	switch wdl {
+	case WDLWin:
+		return 1
+	case WDLCursedWin:
+		return 101
+	case WDLBlessedLoss:
+		return -101
+	case WDLLoss:
+		return -1
+	}
+	return 0
+}
+
+func wdlSign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+// --------------------
+// Root probing
+// --------------------
+
+// ProbeRoot picks the DTZ-optimal move in b: the fastest zeroing win that
+// the fifty-move rule cannot spoil, else a draw, else the slowest loss. The
+// result is that of b after the move, counting the fifty-move rule.
+func (tb *Tablebase) ProbeRoot(b *Board) (Move, WDL, error) {
+	if !tb.CanProbe(b) {
+		return Move{}, WDLDraw, ErrTBMissing
+	}
+
+	cnt50 := int(b.HalfMoveClock)
+	repeated := b.IsRepetition()
+
+	var best Move
+	bestRank := -maxDTZ - 1
+	for _, m := range b.LegalMoves() {
+		b.MakeMove(m)
+
+		var dtz int
+		var err error
+		switch {
+		case b.HalfMoveClock == 0:
+			var wdl WDL
+			wdl, _, err = tb.search(b, false)
+			dtz = dtzBeforeZeroing(-wdl)
+		case b.IsFiftyMoveRule() || b.IsRepetition():
+			dtz = 0
+		default:
+			dtz, err = tb.probeDTZ(b)
+			dtz = -dtz
+			dtz += wdlSign(dtz)
+		}
+		if dtz == 2 && b.IsKingInCheck(b.SideToMove) && !b.HasLegalMoves(b.SideToMove) {
+			dtz = 1 // mates at once
+		}
+		b.UnapplyMove()
+		if err != nil {
+			return Move{}, WDLDraw, err
+		}
+
+		// Wins that convert within the fifty-move rule rank highest, the
+		// faster the better; losses likewise rank by how long they hold
+		var rank int
+		switch {
+		case dtz > 0 && dtz+cnt50 <= 99 && !repeated:
+			rank = maxDTZ - dtz
+		case dtz > 0:
+			rank = maxDTZ/2 - (dtz + cnt50)
+		case dtz < 0 && -dtz*2+cnt50 < 100:
+			rank = -maxDTZ - dtz
+		case dtz < 0:
+			rank = -maxDTZ/2 + (-dtz + cnt50)
+		}
+		if rank > bestRank {
+			best, bestRank = m, rank
+		}
+	}
+
+	if bestRank == -maxDTZ-1 {
+		return Move{}, WDLDraw, ErrTBNoMoves
+	}
+
+	const bound = maxDTZ - 100
+	switch {
+	case bestRank >= bound:
+		return best, WDLWin, nil
+	case bestRank > 0:
+		return best, WDLCursedWin, nil
+	case bestRank == 0:
+		return best, WDLDraw, nil
+	case bestRank > -bound:
+		return best, WDLBlessedLoss, nil
+	}
+	return best, WDLLoss, nil
+}
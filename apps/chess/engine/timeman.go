@@ -0,0 +1,31 @@
+package engine
+
+import "time"
+
+// --------------------
+// Time management
+// --------------------
+
+const (
+	defaultMovesToGo = 30
+	moveOverhead     = 50 * time.Millisecond // transport / GUI latency reserve
+	minThinkTime     = 10 * time.Millisecond
+)
+
+// TimeBudget returns how long to think for one move given the remaining
+// clock time, the increment and the moves left until the next time
+// control (0 if unknown, i.e. sudden death)
+func TimeBudget(remaining, inc time.Duration, movesToGo int) time.Duration {
+	if movesToGo <= 0 {
+		movesToGo = defaultMovesToGo
+	}
+
+	budget := remaining/time.Duration(movesToGo) + inc*3/4
+	if limit := remaining - moveOverhead; budget > limit {
+		budget = limit
+	}
+	if budget < minThinkTime {
+		budget = minThinkTime
+	}
+	return budget
+}
@@ -1,7 +1,6 @@
 package engine
 
 import (
-	"sync"
 	"sync/atomic"
 )
 
@@ -22,59 +21,159 @@ type TTEntry struct {
 	Age      uint8
 }
 
-type TranspositionTable struct {
-	table    map[uint64]TTEntry
-	capacity int
-	mu       sync.RWMutex
-	age      uint8
+// --------------------
+// Table layout
+// --------------------
+
+// The table is a power-of-two array of buckets. Each bucket holds
+// ttBucketSlots entries and fills one 64-byte cache line.
+//
+// An entry is two words: the packed data, and the hash XOR the data. A
+// reader that sees one half of a concurrent write computes a key that no
+// longer matches its hash and treats the slot as a miss, so threads can
+// share the table without locks.
+const (
+	ttBucketSlots = 4
+	ttSlotBytes   = 16
+	ttBucketBytes = ttBucketSlots * ttSlotBytes
+)
 
-	Hits   atomic.Uint64
-	Misses atomic.Uint64
+type ttSlot struct {
+	key  atomic.Uint64 // hash ^ data
+	data atomic.Uint64
 }
 
-func NewTT(capacity int) *TranspositionTable {
+type ttBucket [ttBucketSlots]ttSlot
+
+type TranspositionTable struct {
+	buckets []ttBucket
+	mask    uint64
+	age     atomic.Uint32 // only the low 8 bits are stored in entries
+}
+
+// NewTT allocates a table of at most mb megabytes (minimum one bucket)
+func NewTT(mb int) *TranspositionTable {
+	n := uint64(max(mb, 0)) * 1024 * 1024 / ttBucketBytes
+	size := uint64(1)
+	for size*2 <= n {
+		size *= 2
+	}
+
 	return &TranspositionTable{
-		table:    make(map[uint64]TTEntry, capacity),
-		capacity: capacity,
+		buckets: make([]ttBucket, size),
+		mask:    size - 1,
 	}
 }
 
+// --------------------
+// Entry packing
+// --------------------
+
+// Data word layout (low to high bits):
+//
+//	from:6 to:6 promo:3 flags:4 | type:2 | depth:8 | age:8 | value:27
+const (
+	ttMoveBits   = 19
+	ttTypeShift  = ttMoveBits
+	ttDepthShift = ttTypeShift + 2
+	ttAgeShift   = ttDepthShift + 8
+	ttValShift   = ttAgeShift + 8
+
+	ttValueOffset = 1 << 26 // values are stored unsigned
+	ttNoPromo     = 7
+)
+
+func packMove(m Move) uint64 {
+	promo := uint64(ttNoPromo)
+	if m.Promotion != NoPiece {
+		promo = uint64(m.Promotion)
+	}
+	return uint64(m.From) | uint64(m.To)<<6 | promo<<12 | uint64(m.Flags&0xF)<<15
+}
+
+func unpackMove(d uint64) Move {
+	promo := Piece(d >> 12 & 7)
+	if promo == ttNoPromo {
+		promo = NoPiece
+	}
+	return Move{
+		From:      uint8(d & 0x3F),
+		To:        uint8(d >> 6 & 0x3F),
+		Promotion: promo,
+		Flags:     uint8(d >> 15 & 0xF),
+	}
+}
+
+func packEntry(depth, value int, entryType TTEntryType, best Move, age uint8) uint64 {
+	depth = min(max(depth, 0), 255)
+	return packMove(best) |
+		uint64(entryType)<<ttTypeShift |
+		uint64(depth)<<ttDepthShift |
+		uint64(age)<<ttAgeShift |
+		uint64(value+ttValueOffset)<<ttValShift
+}
+
+func unpackEntry(hash, d uint64) TTEntry {
+	return TTEntry{
+		Hash:     hash,
+		BestMove: unpackMove(d),
+		Type:     TTEntryType(d >> ttTypeShift & 3),
+		Depth:    int(d >> ttDepthShift & 0xFF),
+		Age:      uint8(d >> ttAgeShift),
+		Value:    int(d>>ttValShift) - ttValueOffset,
+	}
+}
+
+// lookup returns the entry stored for hash, if any
+func (tt *TranspositionTable) lookup(hash uint64) (TTEntry, bool) {
+	bucket := &tt.buckets[hash&tt.mask]
+	for i := range bucket {
+		data := bucket[i].data.Load()
+		if bucket[i].key.Load()^data == hash && data != 0 {
+			return unpackEntry(hash, data), true
+		}
+	}
+	return TTEntry{}, false
+}
+
+// --------------------
+// Probe / Store
+// --------------------
+
 func (tt *TranspositionTable) Probe(
 	hash uint64,
 	depth int,
 	alpha int,
 	beta int,
+	ply int,
 ) (int, Move, bool) {
 
-	tt.mu.RLock()
-	entry, ok := tt.table[hash]
-	tt.mu.RUnlock()
-
+	entry, ok := tt.lookup(hash)
 	if !ok {
-		tt.Misses.Add(1)
 		return 0, Move{}, false
 	}
 
-	tt.Hits.Add(1)
-
 	if entry.Depth < depth {
 		return 0, Move{}, false
 	}
 
+	value := denormalizeScore(entry.Value, ply)
+
 	switch entry.Type {
 	case TTExact:
-		return entry.Value, entry.BestMove, true
+		return value, entry.BestMove, true
 	case TTLowerBound:
-		if entry.Value >= beta {
-			return entry.Value, entry.BestMove, true
+		if value >= beta {
+			return value, entry.BestMove, true
 		}
 	case TTUpperBound:
-		if entry.Value <= alpha {
-			return entry.Value, entry.BestMove, true
+		if value <= alpha {
+			return value, entry.BestMove, true
 		}
 	}
 
-	return denormalizeScore(entry.Value, depth), entry.BestMove, true
+	// Bound does not cut at this window
+	return 0, entry.BestMove, false
 }
 
 func normalizeScore(score, ply int) int {
@@ -99,6 +198,9 @@ func denormalizeScore(score, ply int) int {
 	return score
 }
 
+// Store writes an entry, replacing in order of preference: the entry for
+// the same position (unless it is deeper and from this search), an empty
+// slot, or the slot with the lowest depth, older searches first.
 func (tt *TranspositionTable) Store(
 	hash uint64,
 	depth int,
@@ -107,57 +209,78 @@ func (tt *TranspositionTable) Store(
 	best Move,
 	ply int,
 ) {
-	tt.mu.Lock()
-	defer tt.mu.Unlock()
-
 	value = normalizeScore(value, ply)
+	age := uint8(tt.age.Load())
+	bucket := &tt.buckets[hash&tt.mask]
 
-	if old, ok := tt.table[hash]; ok {
-		if old.Age == tt.age && old.Depth > depth {
-			return
+	victim := 0
+	victimScore := int(^uint(0) >> 1)
+	for i := range bucket {
+		data := bucket[i].data.Load()
+		if data == 0 {
+			victim = i
+			break
 		}
-	}
 
-	if len(tt.table) >= tt.capacity {
-		tt.evict()
-	}
+		if bucket[i].key.Load()^data == hash {
+			old := unpackEntry(hash, data)
+			if old.Age == age && old.Depth > depth && entryType != TTExact {
+				return
+			}
+			// An all-node has no best move; keep the one we had
+			if best == (Move{}) {
+				best = old.BestMove
+			}
+			victim = i
+			break
+		}
 
-	tt.table[hash] = TTEntry{
-		Hash:     hash,
-		Depth:    depth,
-		Value:    value,
-		Type:     entryType,
-		BestMove: best,
-		Age:      tt.age,
+		// Each search the entry has survived costs it 8 plies of depth
+		old := unpackEntry(hash, data)
+		score := old.Depth - 8*int(age-old.Age)
+		if score < victimScore {
+			victim, victimScore = i, score
+		}
 	}
+
+	data := packEntry(depth, value, entryType, best, age)
+	bucket[victim].data.Store(data)
+	bucket[victim].key.Store(hash ^ data)
 }
 
+// NewSearch ages the table so entries from earlier searches are replaced
+// first
 func (tt *TranspositionTable) NewSearch() {
-	tt.mu.Lock()
-	tt.age++
-	tt.mu.Unlock()
+	tt.age.Add(1)
+}
+
+// Clear empties the table. It must not run concurrently with a search.
+func (tt *TranspositionTable) Clear() {
+	clear(tt.buckets)
+	tt.age.Store(0)
 }
 
-func (tt *TranspositionTable) evict() {
-	for k, v := range tt.table {
-		if v.Age != tt.age {
-			delete(tt.table, k)
-			return
+// Hashfull returns the permille of sampled slots filled during the current
+// search, as reported by UCI "info hashfull"
+func (tt *TranspositionTable) Hashfull() int {
+	age := uint8(tt.age.Load())
+	buckets := min(len(tt.buckets), 1000/ttBucketSlots)
+
+	used := 0
+	for b := 0; b < buckets; b++ {
+		for i := range tt.buckets[b] {
+			data := tt.buckets[b][i].data.Load()
+			if data != 0 && uint8(data>>ttAgeShift) == age {
+				used++
+			}
 		}
 	}
-	// fallback
-	for k := range tt.table {
-		delete(tt.table, k)
-		return
-	}
+	return used * 1000 / (buckets * ttBucketSlots)
 }
 
 // GetMove returns the best move stored in the TT for the given hash, if any.
 func (tt *TranspositionTable) GetMove(hash uint64) (Move, bool) {
-	tt.mu.RLock()
-	entry, ok := tt.table[hash]
-	tt.mu.RUnlock()
-
+	entry, ok := tt.lookup(hash)
 	if !ok {
 		return Move{}, false
 	}
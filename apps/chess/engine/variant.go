@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --------------------------
+// Variants
+// --------------------------
+
+// Variant selects the rules a board is played by. Chess960 is not one: it
+// only changes the start position and castling, and combines with any of
+// these.
+type Variant uint8
+
+const (
+	VariantStandard      Variant = iota
+	VariantKingOfTheHill         // a king reaching d4, e4, d5 or e5 wins
+	VariantThreeCheck            // the third check given wins
+	VariantAtomic                // captures explode, taking the king wins
+)
+
+var variantNames = [...]string{
+	VariantStandard:      "Standard",
+	VariantKingOfTheHill: "King of the Hill",
+	VariantThreeCheck:    "Three-check",
+	VariantAtomic:        "Atomic",
+}
+
+// String returns the variant's name as used in PGN Variant tags
+func (v Variant) String() string {
+	if int(v) < len(variantNames) {
+		return variantNames[v]
+	}
+	return fmt.Sprintf("Variant(%d)", v)
+}
+
+// ParseVariant accepts a variant's name, ignoring case, spaces and dashes,
+// or the UCI_Variant names (chess, kingofthehill, 3check, atomic)
+func ParseVariant(name string) (Variant, error) {
+	key := strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name))
+	switch key {
+	case "standard", "chess":
+		return VariantStandard, nil
+	case "kingofthehill", "koth":
+		return VariantKingOfTheHill, nil
+	case "threecheck", "3check":
+		return VariantThreeCheck, nil
+	case "atomic":
+		return VariantAtomic, nil
+	}
+	return VariantStandard, fmt.Errorf("unknown variant %q", name)
+}
+
+// SetVariant switches the rules b is played by
+func (b *Board) SetVariant(v Variant) {
+	b.Variant = v
+	b.Hash = b.BoardHash()
+}
+
+// hillSquares are d4, e4, d5 and e5
+const hillSquares uint64 = 0x0000001818000000
+
+// IsVariantLoss reports whether the side to move has lost by a rule of the
+// variant: the other king reached the hill, the third check was given, or
+// its own king was blown up. Such a position has no legal moves.
+func (b *Board) IsVariantLoss() bool {
+	us := b.SideToMove
+	switch b.Variant {
+	case VariantKingOfTheHill:
+		return b.Pieces[us^1][King]&hillSquares != 0
+	case VariantThreeCheck:
+		return b.Checks[us^1] >= 3
+	case VariantAtomic:
+		return b.Pieces[us][King] == 0
+	}
+	return false
+}
+
+// --------------------------
+// Atomic
+// --------------------------
+
+// attackedForKing reports whether a king would be in check on sq. In atomic
+// chess a king touching the enemy king cannot be checked, since taking it
+// would blow up the taker's own king too.
+func (b *Board) attackedForKing(sq uint8, by Color) bool {
+	if b.Variant == VariantAtomic && KingAttacks[sq]&b.Pieces[by][King] != 0 {
+		return false
+	}
+	return b.squareAttacked(sq, by)
+}
+
+// explode removes the capturing piece on sq and every piece but pawns
+// around it, with the castling rights of any rook or king caught in it
+func (b *Board) explode(sq uint8) {
+	blast := KingAttacks[sq]
+	for c := Color(0); c < ColorNB; c++ {
+		for p := Piece(0); p < PieceNB; p++ {
+			b.Pieces[c][p] &^= bit(sq)
+			if p != Pawn {
+				b.Pieces[c][p] &^= blast
+			}
+		}
+	}
+
+	for i, rook := range b.CastleRooks {
+		c := Color(i / 2)
+		if b.Pieces[c][Rook]&bit(rook) == 0 || b.Pieces[c][King] == 0 {
+			b.Castling &^= 1 << i
+		}
+	}
+}
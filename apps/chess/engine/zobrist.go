@@ -9,14 +9,13 @@ const (
 	Squares           = 64
 )
 
-// Zobrist table
-var Zobrist [2][6][64]uint64 // [color][piece][square]
-// var ZobristSide uint64       // side to move
+// Zobrist tables
 var (
-	ZPiece  [ColorNB][PieceNB][64]uint64
+	ZPiece  [ColorNB][PieceNB][64]uint64 // [color][piece][square]
 	ZCastle [16]uint64
 	ZEP     [8]uint64
 	ZSide   uint64
+	ZChecks [ColorNB][4]uint64 // Three-check: [color][checks given]
 )
 
 func init() {
@@ -24,7 +23,7 @@ func init() {
 	for color := 0; color < 2; color++ {
 		for piece := 0; piece < 6; piece++ {
 			for sq := 0; sq < 64; sq++ {
-				Zobrist[color][piece][sq] = r.Uint64()
+				ZPiece[color][piece][sq] = r.Uint64()
 			}
 		}
 	}
@@ -37,6 +36,12 @@ func init() {
 	}
 
 	ZSide = r.Uint64()
+
+	for color := 0; color < 2; color++ {
+		for n := 0; n < 4; n++ {
+			ZChecks[color][n] = r.Uint64()
+		}
+	}
 }
 
 func (b *Board) BoardHash() uint64 {
@@ -53,15 +58,16 @@ func (b *Board) BoardHash() uint64 {
 	}
 
 	h ^= ZCastle[b.Castling]
-
-	if b.EnPassant != 255 {
-		h ^= ZEP[b.EnPassant%8]
-	}
+	h ^= b.epKey(b.EnPassant, b.SideToMove)
 
 	if b.SideToMove == Black {
 		h ^= ZSide
 	}
 
+	if b.Variant == VariantThreeCheck {
+		h ^= ZChecks[White][b.Checks[White]] ^ ZChecks[Black][b.Checks[Black]]
+	}
+
 	return h
 }
 
@@ -71,14 +77,12 @@ func (b *Board) UpdateHash(
 	piece Piece,
 	captured Piece,
 	oldCastle uint8,
-	oldEP uint8,
+	oldEPKey uint64,
 ) {
 	// Remove old state
 	b.Hash ^= ZSide
 	b.Hash ^= ZCastle[oldCastle]
-	if oldEP != 255 {
-		b.Hash ^= ZEP[oldEP%8]
-	}
+	b.Hash ^= oldEPKey
 
 	// 1. Remove pawn from FROM square
 	b.Hash ^= ZPiece[color][piece][m.From]
@@ -103,9 +107,24 @@ func (b *Board) UpdateHash(
 		b.Hash ^= ZPiece[color^1][captured][capSq]
 	}
 
+	// 4. Handle castling rook
+	if m.Flags&MoveCastle != 0 {
+		rookFrom, rookTo := b.castleRookSquares(m.To)
+		b.Hash ^= ZPiece[color][Rook][rookFrom]
+		b.Hash ^= ZPiece[color][Rook][rookTo]
+	}
+
 	// Add new state
 	b.Hash ^= ZCastle[b.Castling]
-	if b.EnPassant != 255 {
-		b.Hash ^= ZEP[b.EnPassant%8]
+	b.Hash ^= b.epKey(b.EnPassant, color^1)
+}
+
+// epKey is the en-passant square's share of the hash. As in the Polyglot
+// key it only counts when a pawn of by can capture there, so a double push
+// nobody can take hashes like any other way of reaching the position.
+func (b *Board) epKey(ep uint8, by Color) uint64 {
+	if ep == NoSquare || PawnAttacks(by^1, ep)&b.Pieces[by][Pawn] == 0 {
+		return 0
 	}
+	return ZEP[ep%8]
 }
@@ -0,0 +1,137 @@
+package game
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+)
+
+// --------------------------
+// Bot strength levels
+// --------------------------
+
+// BotLevel limits how deep and how long the engine searches
+type BotLevel struct {
+	Level    int
+	Name     string
+	MaxDepth int
+	MaxTime  time.Duration // upper bound per move, on top of the clock budget
+}
+
+// botTTMB sizes each bot's transposition table in megabytes
+const botTTMB = 16
+
+// BotThreads is the number of search threads per engine move (Lazy SMP).
+// Every bot game searches at once, so by default each takes a single core.
+var BotThreads = 1
+
+// BotBook, if set, supplies the engine's opening moves so its play varies
+// from game to game
+var BotBook *engine.Book
+
+// BotTB, if set, supplies perfect play once few enough pieces remain
+var BotTB *engine.Tablebase
+
+var botLevels = []BotLevel{
+	{Level: 1, Name: "Beginner", MaxDepth: 1, MaxTime: 200 * time.Millisecond},
+	{Level: 2, Name: "Casual", MaxDepth: 2, MaxTime: 500 * time.Millisecond},
+	{Level: 3, Name: "Club", MaxDepth: 4, MaxTime: time.Second},
+	{Level: 4, Name: "Expert", MaxDepth: 6, MaxTime: 3 * time.Second},
+	{Level: 5, Name: "Master", MaxDepth: 64, MaxTime: 10 * time.Second},
+}
+
+// FindBotLevel returns the strength level with the given number
+func FindBotLevel(level int) (BotLevel, error) {
+	for _, l := range botLevels {
+		if l.Level == level {
+			return l, nil
+		}
+	}
+	return BotLevel{}, errors.New("invalid bot level")
+}
+
+// ListBotLevels returns all strength levels (for UI)
+func ListBotLevels() []BotLevel {
+	out := make([]BotLevel, len(botLevels))
+	copy(out, botLevels)
+	return out
+}
+
+// --------------------------
+// Bot opponent
+// --------------------------
+
+// Bot is the engine playing one side of a game
+type Bot struct {
+	Color engine.Color
+	Level BotLevel
+
+	tt       *engine.TranspositionTable
+	thinking bool // a search is running; guarded by Game.mu
+}
+
+// NewBotGame creates a game in which the engine plays botColor against
+// playerID
+func NewBotGame(mode *GameMode, level BotLevel, botColor engine.Color, playerID string) *Game {
+	return newGame(mode, playerID, &Bot{
+		Color: botColor,
+		Level: level,
+		tt:    engine.NewTT(botTTMB),
+	})
+}
+
+// IsBotTurn reports whether the engine is to move in an ongoing game
+func (g *Game) IsBotTurn() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.isBotTurn()
+}
+
+func (g *Game) isBotTurn() bool {
+	return g.Bot != nil && g.State == GameOngoing && g.Board.SideToMove == g.Bot.Color
+}
+
+// PlayBotMove searches the position and applies the engine's move. The
+// search runs without holding the game lock, on the bot's own clock, within
+// a budget derived from its remaining time. It returns false when it is not
+// the engine's turn, another search is already running, or the game ended
+// (flag, draw claim) before the move could be applied.
+func (g *Game) PlayBotMove() bool {
+	g.mu.Lock()
+	if !g.isBotTurn() || g.Bot.thinking {
+		g.mu.Unlock()
+		return false
+	}
+	g.Bot.thinking = true
+
+	bot := g.Bot
+	board := g.Board.Clone()
+	budget := bot.Level.MaxTime
+	if g.Mode.TimeNs > 0 {
+		remaining := time.Duration(g.Clock.Remaining(bot.Color))
+		budget = min(budget, engine.TimeBudget(remaining, time.Duration(g.Mode.Increment), 0))
+	}
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		bot.thinking = false
+		g.mu.Unlock()
+	}()
+
+	searcher := &engine.Searcher{TT: bot.tt, Threads: BotThreads, Book: BotBook, TB: BotTB}
+	res := searcher.Search(board, bot.Level.MaxDepth, budget)
+
+	move := res.BestMove
+	if res.Depth == 0 && !res.Book && !res.TBHit {
+		// Out of time before depth 1 completed: any legal move beats a flag
+		legal := board.LegalMoves()
+		if len(legal) == 0 {
+			return false
+		}
+		move = legal[0]
+	}
+
+	return g.ApplyMove(move, 0)
+}
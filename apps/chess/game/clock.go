@@ -51,13 +51,17 @@ func (gc *GameClock) Start(color engine.Color) {
 func (gc *GameClock) Stop(color engine.Color, lagCompNs int64) {
 	now := monoNow()
 	c := gc.clock(color)
-	elapsed := now - c.LastStartNs - lagCompNs
-	if elapsed < 0 {
-		elapsed = 0
-	}
-	c.RemainingNs -= elapsed
-	if c.RemainingNs < 0 {
-		c.RemainingNs = 0
+
+	// The clock only runs once the opponent has made their first move
+	if c.Running {
+		elapsed := now - c.LastStartNs - lagCompNs
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		c.RemainingNs -= elapsed
+		if c.RemainingNs < 0 {
+			c.RemainingNs = 0
+		}
 	}
 	c.RemainingNs += gc.IncNs
 	c.Running = false
@@ -66,6 +70,47 @@ func (gc *GameClock) Stop(color engine.Color, lagCompNs int64) {
 	gc.Turn++
 }
 
+// Remaining returns the live time left for color, including the
+// currently running period, never below zero
+func (gc *GameClock) Remaining(color engine.Color) int64 {
+	return max(gc.live(color), 0)
+}
+
+// live is Remaining without the floor, so lag compensation can be weighed
+// against time already overdrawn
+func (gc *GameClock) live(color engine.Color) int64 {
+	c := gc.clock(color)
+	if !c.Running {
+		return c.RemainingNs
+	}
+	return c.RemainingNs - (monoNow() - c.LastStartNs)
+}
+
+// Expired reports whether color's running clock has run out, granting
+// lagCompNs of network lag compensation
+func (gc *GameClock) Expired(color engine.Color, lagCompNs int64) bool {
+	return gc.clock(color).Running && gc.live(color)+lagCompNs <= 0
+}
+
+// Halt stops both clocks for good when the game ends, charging the time
+// used so far but no increment
+func (gc *GameClock) Halt() {
+	for color := engine.Color(0); color < engine.ColorNB; color++ {
+		c := gc.clock(color)
+		if c.Running {
+			c.RemainingNs = gc.Remaining(color)
+			c.Running = false
+		}
+	}
+}
+
+// Flag stops color's clock at zero
+func (gc *GameClock) Flag(color engine.Color) {
+	c := gc.clock(color)
+	c.RemainingNs = 0
+	c.Running = false
+}
+
 func (gc *GameClock) clock(color engine.Color) *Clock {
 	if color == 0 {
 		return &gc.White
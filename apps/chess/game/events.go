@@ -1 +1,73 @@
 package game
+
+import (
+	"sync"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+)
+
+// --------------------------
+// Game events
+// --------------------------
+
+type EventType int
+
+const (
+	EventMove     EventType = iota // a move was applied
+	EventGameOver                  // the game ended without a move (flag, claim, ...)
+)
+
+type Event struct {
+	Type   EventType
+	Seq    uint64
+	State  GameState
+	Winner engine.Color
+}
+
+// --------------------------
+// Subscribers
+// --------------------------
+
+// eventBufferSize is how many events a slow subscriber can lag behind
+// before further events are dropped for it
+const eventBufferSize = 16
+
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+// Subscribe returns a channel receiving the game's events and a function
+// that cancels the subscription. Events are dropped, never blocked on, when
+// the subscriber falls behind.
+func (g *Game) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+
+	g.events.mu.Lock()
+	if g.events.subs == nil {
+		g.events.subs = make(map[chan Event]struct{})
+	}
+	g.events.subs[ch] = struct{}{}
+	g.events.mu.Unlock()
+
+	cancel := func() {
+		g.events.mu.Lock()
+		defer g.events.mu.Unlock()
+		if _, ok := g.events.subs[ch]; ok {
+			delete(g.events.subs, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+func (g *Game) publish(e Event) {
+	g.events.mu.Lock()
+	defer g.events.mu.Unlock()
+	for ch := range g.events.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
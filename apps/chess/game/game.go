@@ -3,7 +3,9 @@ package game
 import (
 	"context"
 	"log"
+	"math/rand/v2"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lordsonvimal/synergy/apps/chess/engine"
@@ -35,10 +37,15 @@ const (
 	GameAbandoned                          // player left the game
 	GameDisconnected                       // network error causing game to stop
 	GameInvalid                            // invalid state
+	GameKingOfTheHill                      // king reached the centre
+	GameThreeCheck                         // third check given
+	GameKingExploded                       // atomic: king blown up
 )
 
 type Game struct {
 	ID        string
+	Mode      GameMode
+	StartedAt time.Time
 	Board     *engine.Board
 	Clock     GameClock
 	WAL       *WAL
@@ -46,34 +53,170 @@ type Game struct {
 	Seq       uint64
 	State     GameState
 	Winner    engine.Color // valid after game over
+	Bot       *Bot         // engine opponent, nil in human vs human games
+	StartFEN  string       // start position of a Chess960 game, "" for the standard one
+	PlayerID  string       // the human who created the game, "" if unknown
 
 	mu             sync.RWMutex
 	legalMoveCache map[engine.Color]bool // cache per side
+	events         eventHub
+	flagTimer      *time.Timer
+	flagGen        uint64 // invalidates timers that fired after being replaced
+	lastActive     time.Time
 }
 
-func NewGame(mode *GameMode) *Game {
+// NewGame creates a human vs human game for playerID, who plays both sides
+func NewGame(mode *GameMode, playerID string) *Game {
+	return newGame(mode, playerID, nil)
+}
+
+// newGame creates a game and its WAL, whose header records everything
+// needed to set the game up again on recovery
+func newGame(mode *GameMode, playerID string, bot *Bot) *Game {
 	board := engine.NewBoard()
+	startFEN := ""
+	if mode.Variant == VariantChess960 {
+		// The number is always in range
+		_ = board.ResetChess960(rand.IntN(960))
+		startFEN = board.FEN()
+	}
+	board.SetVariant(mode.Rules())
 
 	id := uuid.New().String()
 
 	// 2. 5 minutes per side with 2-second increment
 	gc := NewClock(mode.TimeNs, mode.Increment)
 
-	wal, err := NewWAL("game_" + id + ".wal")
+	startedAt := time.Now()
+	header := WALHeader{
+		GameID:    id,
+		Mode:      mode.Name,
+		StartFEN:  startFEN,
+		CreatedNs: startedAt.UnixNano(),
+	}
+	for c := engine.Color(0); c < engine.ColorNB; c++ {
+		if bot != nil && bot.Color == c {
+			header.Player(c).BotLevel = bot.Level.Level
+		} else {
+			header.Player(c).ID = playerID
+		}
+	}
+	wal, err := NewWAL(walPath(id), header)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// 4. Create the Game struct
 	return &Game{
-		ID:     id,
-		Board:  board,
-		Clock:  gc,
-		WAL:    wal,
-		Seq:    0,
-		State:  GameOngoing,
-		Winner: engine.NoColor,
+		ID:        id,
+		Mode:      *mode,
+		StartedAt: startedAt,
+		Board:     board,
+		StartFEN:  startFEN,
+		PlayerID:  playerID,
+		Clock:     gc,
+		WAL:       wal,
+		Seq:       0,
+		State:     GameOngoing,
+		Winner:    engine.NoColor,
+		Bot:       bot,
+
+		lastActive: startedAt,
+	}
+}
+
+// StartBoard returns a new board at the game's start position, playing by
+// the mode's rules
+func (g *Game) StartBoard() (*engine.Board, error) {
+	b := engine.NewBoard()
+	if g.StartFEN != "" {
+		if err := b.SetFEN(g.StartFEN); err != nil {
+			return nil, err
+		}
+	}
+	b.SetVariant(g.Mode.Rules())
+	return b, nil
+}
+
+// Outcome returns the game state and the winner (NoColor unless decisive)
+func (g *Game) Outcome() (GameState, engine.Color) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.State, g.Winner
+}
+
+// LastActive returns when a player last selected a square, moved or
+// claimed, or when the game ended
+func (g *Game) LastActive() time.Time {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.lastActive
+}
+
+// SideToMove returns the color whose turn it is
+func (g *Game) SideToMove() engine.Color {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Board.SideToMove
+}
+
+// BoardCopy returns a copy of the current board, safe to read while the
+// game goes on
+func (g *Game) BoardCopy() *engine.Board {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Board.Clone()
+}
+
+// MoveList returns the moves played so far in SAN
+func (g *Game) MoveList() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Board.SANHistory()
+}
+
+// GameSnapshot is a consistent view of a game, taken under its lock
+type GameSnapshot struct {
+	Seq      uint64
+	FEN      string
+	WhiteNs  int64    // live time left on White's clock
+	BlackNs  int64    // live time left on Black's clock
+	UCIMoves []string // as logged, in the board's castling notation
+	SANMoves []string
+	State    GameState
+	Winner   engine.Color
+	Check    bool
+}
+
+// Snapshot returns the game's current position, clocks and moves
+func (g *Game) Snapshot() GameSnapshot {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	snap := GameSnapshot{
+		Seq:      g.Seq,
+		FEN:      g.Board.FEN(),
+		WhiteNs:  g.Clock.Remaining(engine.White),
+		BlackNs:  g.Clock.Remaining(engine.Black),
+		SANMoves: g.Board.SANHistory(),
+		State:    g.State,
+		Winner:   g.Winner,
+		Check:    g.Board.IsKingInCheck(g.Board.SideToMove),
+	}
+	for _, e := range g.WAL.LoadFromMemory() {
+		if e.Type == WALEventMove || e.Type == "" {
+			snap.UCIMoves = append(snap.UCIMoves, e.MoveUCI)
+		}
 	}
+	return snap
+}
+
+// ParseUCIMove resolves a UCI move against the current position's legal
+// moves
+func (g *Game) ParseUCIMove(s string) (engine.Move, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Board.ParseUCIMove(s)
 }
 
 // --------------------------
@@ -125,38 +268,142 @@ func (g *Game) ApplyMove(m engine.Move, lagCompNs int64) bool {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	// 0. Prevent moves if game is already over
-	if g.State != GameOngoing {
+	// 0. Prevent moves if game is already over, or can no longer be logged
+	if g.State != GameOngoing || g.WAL.Closed() {
 		return false
 	}
 
 	color := g.Board.SideToMove
-	g.Clock.Stop(color, lagCompNs)
+	prevBoard, prevClock := g.Board.Clone(), g.Clock
+
+	// 1. The mover may already have run out of time
+	if g.Clock.Expired(color, lagCompNs) {
+		g.flag(color)
+		return false
+	}
 
 	if !g.Board.MakeMove(m) {
 		return false
 	}
 
+	// Log the move as the board recorded it, castling in its notation
+	last := g.Board.MoveStack[len(g.Board.MoveStack)-1]
+	played := engine.Move{From: last.From, To: last.To, Promotion: last.Promotion, Flags: last.Flags}
+
+	g.Clock.Stop(color, lagCompNs)
+
 	// Reset legal move cache since board changed
 	g.legalMoveCache = nil
 
-	g.Clock.Start(color ^ 1)
+	// Update game state after each move; the opponent's clock only starts
+	// if the game goes on
+	g.UpdateGameState()
+	if g.State == GameOngoing {
+		g.Clock.Start(color ^ 1)
+	}
 
-	g.Seq++
-	g.WAL.Append(WALEvent{
-		Seq:       g.Seq,
-		MoveUCI:   m.ToUCI(),
+	// A move that cannot be logged is taken back
+	err := g.appendWAL(WALEvent{
+		Seq:       g.Seq + 1,
+		Type:      WALEventMove,
+		MoveUCI:   g.Board.UCI(played),
 		ServerNs:  monoNow(),
 		LagCompNs: lagCompNs,
 		WRem:      g.Clock.White.RemainingNs,
 		BRem:      g.Clock.Black.RemainingNs,
 	})
+	if err != nil {
+		g.Board, g.Clock = prevBoard, prevClock
+		g.State, g.Winner = GameOngoing, engine.NoColor
+		g.legalMoveCache = nil
+		return false
+	}
+	g.Seq++
+	g.lastActive = time.Now()
 
-	g.ClearSelection()  // After move, clear selection
-	g.UpdateGameState() // Update game state after each move
+	g.ClearSelection() // After move, clear selection
+	g.scheduleFlag()
+
+	g.publish(Event{Type: EventMove, Seq: g.Seq, State: g.State, Winner: g.Winner})
 	return true
 }
 
+// --------------------------
+// Clock flagging
+// --------------------------
+
+// scheduleFlag arms a timer that flags the side to move when its running
+// clock reaches zero. Any previously armed timer is cancelled.
+func (g *Game) scheduleFlag() {
+	g.stopFlagTimer()
+
+	color := g.Board.SideToMove
+	if g.State != GameOngoing || !g.Clock.clock(color).Running {
+		return
+	}
+
+	gen := g.flagGen
+	remaining := time.Duration(g.Clock.Remaining(color))
+	g.flagTimer = time.AfterFunc(remaining, func() { g.onFlagTimer(gen) })
+}
+
+func (g *Game) onFlagTimer(gen uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// A move re-armed the timer while this one was firing
+	if gen != g.flagGen || g.State != GameOngoing {
+		return
+	}
+
+	color := g.Board.SideToMove
+	if !g.Clock.Expired(color, 0) {
+		g.scheduleFlag()
+		return
+	}
+	g.flag(color)
+}
+
+// flag ends the game on time for color, records it in the WAL and
+// notifies subscribers. Caller must hold g.mu.
+func (g *Game) flag(color engine.Color) {
+	g.Clock.Flag(color)
+	g.Clock.Halt()
+	g.resolveFlag(color)
+	g.stopFlagTimer()
+
+	g.Seq++
+	g.lastActive = time.Now()
+	g.appendWAL(WALEvent{
+		Seq:      g.Seq,
+		Type:     WALEventFlag,
+		ServerNs: monoNow(),
+		WRem:     g.Clock.White.RemainingNs,
+		BRem:     g.Clock.Black.RemainingNs,
+	})
+
+	g.ClearSelection()
+	g.publish(Event{Type: EventGameOver, Seq: g.Seq, State: g.State, Winner: g.Winner})
+}
+
+// appendWAL logs e, reporting a failure. A flag, draw or abandonment still
+// ends the game in memory when it cannot be logged. Caller must hold g.mu.
+func (g *Game) appendWAL(e WALEvent) error {
+	err := g.WAL.Append(e)
+	if err != nil {
+		logger.Error(context.Background()).Err(err).Str("game", g.ID).Str("event", e.Type).Msg("Failed to log game event")
+	}
+	return err
+}
+
+func (g *Game) stopFlagTimer() {
+	if g.flagTimer != nil {
+		g.flagTimer.Stop()
+		g.flagTimer = nil
+	}
+	g.flagGen++
+}
+
 // --------------------------
 // Update game state after a move
 // --------------------------
@@ -171,6 +418,14 @@ func (g *Game) UpdateGameState() {
 
 	color := g.Board.SideToMove ^ 1 // The player who just moved
 
+	// 0. Variant wins: the mover's king reached the hill, gave the third
+	// check or blew up the other king
+	if g.Board.IsVariantLoss() {
+		g.State = variantWinState(g.Board.Variant)
+		g.Winner = color
+		return
+	}
+
 	// 1. Check checkmate
 	if g.IsCheckmate() {
 		g.State = GameCheckmate
@@ -185,15 +440,15 @@ func (g *Game) UpdateGameState() {
 		return
 	}
 
-	// 3. Fifty-move rule
-	// if g.Board.HalfMoveClock >= 100 {
-	// 	g.State = GameDrawFiftyMove
-	// 	g.Winner = engine.NoColor
-	// 	return
-	// }
+	// 3. Seventy-five-move rule (automatic)
+	if g.Board.IsSeventyFiveMoveRule() {
+		g.State = GameDrawFiftyMove
+		g.Winner = engine.NoColor
+		return
+	}
 
-	// 4. Threefold repetition
-	if g.Board.IsThreefoldRepetition() {
+	// 4. Fivefold repetition (automatic)
+	if g.Board.IsFivefoldRepetition() {
 		g.State = GameDrawThreefoldRepetition
 		g.Winner = engine.NoColor
 		return
@@ -207,22 +462,134 @@ func (g *Game) UpdateGameState() {
 	}
 
 	// 6. Clock flag (time out)
-	// if g.Clock.White.RemainingNs <= 0 {
-	// 	g.State = GameClockFlagged
-	// 	g.Winner = engine.Black
-	// 	return
-	// }
-	// if g.Clock.Black.RemainingNs <= 0 {
-	// 	g.State = GameClockFlagged
-	// 	g.Winner = engine.White
-	// 	return
-	// }
+	if g.Clock.White.RemainingNs <= 0 {
+		g.resolveFlag(engine.White)
+		return
+	}
+	if g.Clock.Black.RemainingNs <= 0 {
+		g.resolveFlag(engine.Black)
+		return
+	}
 
 	// 7. If none of the above, game ongoing
 	g.State = GameOngoing
 	g.Winner = engine.NoColor
 }
 
+// variantWinState is the result state of a win by v's own rule
+func variantWinState(v engine.Variant) GameState {
+	switch v {
+	case engine.VariantKingOfTheHill:
+		return GameKingOfTheHill
+	case engine.VariantThreeCheck:
+		return GameThreeCheck
+	case engine.VariantAtomic:
+		return GameKingExploded
+	}
+	return GameInvalid
+}
+
+// --------------------------
+// Helper: clock flag result
+// --------------------------
+
+// resolveFlag ends the game after color ran out of time. The opponent wins
+// unless they could not checkmate by any series of legal moves, in which
+// case the game is drawn.
+func (g *Game) resolveFlag(color engine.Color) {
+	if !g.Board.HasMatingMaterial(color ^ 1) {
+		g.State = GameDrawInsufficientMaterial
+		g.Winner = engine.NoColor
+		return
+	}
+	g.State = GameClockFlagged
+	g.Winner = color ^ 1
+}
+
+// --------------------------
+// Draw claims (threefold repetition / fifty-move rule)
+// --------------------------
+
+// CanClaimDraw reports whether the current position allows a draw claim
+func (g *Game) CanClaimDraw() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.State == GameOngoing && (g.Board.IsThreefoldRepetition() || g.Board.IsFiftyMoveRule())
+}
+
+// ClaimDraw ends the game as a draw if a claim is valid.
+// Repetition takes precedence when both rules apply.
+func (g *Game) ClaimDraw() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.State != GameOngoing || g.WAL.Closed() || !g.acceptDrawClaim() {
+		return false
+	}
+	g.stopFlagTimer()
+	g.Clock.Halt()
+
+	g.Seq++
+	g.lastActive = time.Now()
+	g.appendWAL(WALEvent{
+		Seq:      g.Seq,
+		Type:     WALEventDraw,
+		ServerNs: monoNow(),
+		WRem:     g.Clock.White.RemainingNs,
+		BRem:     g.Clock.Black.RemainingNs,
+	})
+
+	g.publish(Event{Type: EventGameOver, Seq: g.Seq, State: g.State, Winner: g.Winner})
+	return true
+}
+
+// --------------------------
+// Abandonment
+// --------------------------
+
+// Abandon ends an ongoing game nobody is playing any more, with no winner,
+// and records it in the WAL. It reports whether the game was ongoing.
+func (g *Game) Abandon() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.State != GameOngoing {
+		return false
+	}
+	g.State = GameAbandoned
+	g.Winner = engine.NoColor
+	g.stopFlagTimer()
+	g.Clock.Halt()
+
+	g.Seq++
+	g.lastActive = time.Now()
+	g.appendWAL(WALEvent{
+		Seq:      g.Seq,
+		Type:     WALEventAbandon,
+		ServerNs: monoNow(),
+		WRem:     g.Clock.White.RemainingNs,
+		BRem:     g.Clock.Black.RemainingNs,
+	})
+
+	g.ClearSelection()
+	g.publish(Event{Type: EventGameOver, Seq: g.Seq, State: g.State, Winner: g.Winner})
+	return true
+}
+
+// acceptDrawClaim ends the game as a draw if the position allows a claim
+func (g *Game) acceptDrawClaim() bool {
+	switch {
+	case g.Board.IsThreefoldRepetition():
+		g.State = GameDrawThreefoldRepetition
+	case g.Board.IsFiftyMoveRule():
+		g.State = GameDrawFiftyMove
+	default:
+		return false
+	}
+	g.Winner = engine.NoColor
+	return true
+}
+
 // --------------------------
 // Helper: cached HasLegalMoves
 // --------------------------
@@ -319,6 +686,7 @@ func (g *Game) ClearSelection() {
 
 func (g *Game) SelectSquare(ctx context.Context, square uint8) {
 	g.mu.Lock()
+	g.lastActive = time.Now()
 
 	color, _, ok := g.Board.PieceAt(square)
 	// If no piece or piece is not ours, clear selection
@@ -336,7 +704,7 @@ func (g *Game) SelectSquare(ctx context.Context, square uint8) {
 
 	targets := make([]uint8, 0, len(moves))
 	for _, m := range moves {
-		targets = append(targets, m.To)
+		targets = append(targets, g.Board.MoveTarget(m))
 	}
 
 	g.Selection = &SelectionState{
@@ -3,6 +3,17 @@ package game
 import (
 	"errors"
 	"strings"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+)
+
+// Variants a mode can be played in
+const (
+	VariantStandard      = "Standard"
+	VariantChess960      = "Chess960"
+	VariantKingOfTheHill = "King of the Hill"
+	VariantThreeCheck    = "Three-check"
+	VariantAtomic        = "Atomic"
 )
 
 type GameMode struct {
@@ -25,19 +36,43 @@ func init() {
 			Name:      "Standard 5+2",
 			TimeNs:    5 * 60 * 1_000_000_000,
 			Increment: 2 * 1_000_000_000,
-			Variant:   "Standard",
+			Variant:   VariantStandard,
 		},
 		{
 			Name:      "Blitz 3+0",
 			TimeNs:    3 * 60 * 1_000_000_000,
 			Increment: 0,
-			Variant:   "Standard",
+			Variant:   VariantStandard,
 		},
 		{
 			Name:      "Rapid 10+5",
 			TimeNs:    10 * 60 * 1_000_000_000,
 			Increment: 5 * 1_000_000_000,
-			Variant:   "Standard",
+			Variant:   VariantStandard,
+		},
+		{
+			Name:      "Chess960 5+2",
+			TimeNs:    5 * 60 * 1_000_000_000,
+			Increment: 2 * 1_000_000_000,
+			Variant:   VariantChess960,
+		},
+		{
+			Name:      "King of the Hill 5+2",
+			TimeNs:    5 * 60 * 1_000_000_000,
+			Increment: 2 * 1_000_000_000,
+			Variant:   VariantKingOfTheHill,
+		},
+		{
+			Name:      "Three-check 5+2",
+			TimeNs:    5 * 60 * 1_000_000_000,
+			Increment: 2 * 1_000_000_000,
+			Variant:   VariantThreeCheck,
+		},
+		{
+			Name:      "Atomic 5+2",
+			TimeNs:    5 * 60 * 1_000_000_000,
+			Increment: 2 * 1_000_000_000,
+			Variant:   VariantAtomic,
 		},
 	}
 
@@ -68,6 +103,16 @@ func ListGameModes() []GameMode {
 	return out
 }
 
+// Rules returns the engine rules the mode is played by. Chess960 uses the
+// standard rules from another start position.
+func (gm GameMode) Rules() engine.Variant {
+	v, err := engine.ParseVariant(gm.Variant)
+	if err != nil {
+		return engine.VariantStandard
+	}
+	return v
+}
+
 // --------------------------
 // Helpers
 // --------------------------
@@ -0,0 +1,185 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+)
+
+// --------------------------
+// Crash recovery
+// --------------------------
+
+// RecoverGames rebuilds every game with a WAL file in dir. Files that fail
+// to load are skipped; their errors are joined into the returned error
+// alongside the games that did load.
+func RecoverGames(dir string) ([]*Game, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "game_*.wal"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Left by a crash during compaction; the WAL it was replacing is intact
+	if tmps, err := filepath.Glob(filepath.Join(dir, "game_*.wal.tmp")); err == nil {
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+	}
+
+	var games []*Game
+	var errs []error
+	for _, path := range paths {
+		g, err := LoadGame(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		games = append(games, g)
+	}
+	return games, errors.Join(errs...)
+}
+
+// LoadGame rebuilds a game from its WAL file: the header sets it up, the
+// moves are replayed onto a fresh board, and the clocks are restored from
+// the last event. A game still in progress resumes with the side to move's
+// clock running from now, so the downtime is not charged to it. A finished
+// game's WAL is closed, leaving the game read-only.
+//
+// WALs that predate headers are taken to be games of the first mode.
+func LoadGame(path string) (*Game, error) {
+	wal, err := OpenWAL(path)
+	if err != nil {
+		return nil, err
+	}
+	g, err := replayWAL(wal)
+	if err != nil {
+		wal.Close()
+		return nil, err
+	}
+
+	if g.State != GameOngoing {
+		wal.Close()
+	}
+	return g, nil
+}
+
+// Restore rebuilds a game from a header and events kept outside a WAL file,
+// as LoadGame does. Its WAL is in memory only and refuses appends, so the
+// game is read-only: one still in progress is restored abandoned, with an
+// abandon event after the others.
+func Restore(header WALHeader, events []WALEvent) (*Game, error) {
+	g, err := replayWAL(NewMemoryWAL(header, events))
+	if err != nil {
+		return nil, err
+	}
+	g.stopFlagTimer()
+	if g.State != GameOngoing {
+		return g, nil
+	}
+
+	abandon := WALEvent{
+		Seq:      g.Seq + 1,
+		Type:     WALEventAbandon,
+		ServerNs: monoNow(),
+		WRem:     g.Clock.White.RemainingNs,
+		BRem:     g.Clock.Black.RemainingNs,
+	}
+	g, err = replayWAL(NewMemoryWAL(header, append(events[:len(events):len(events)], abandon)))
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func replayWAL(wal *WAL) (*Game, error) {
+	header := wal.Header()
+	events := wal.LoadFromMemory()
+
+	mode := ListGameModes()[0]
+	if header.Mode != "" {
+		m, err := FindGameModeByName(header.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("mode %q: %w", header.Mode, err)
+		}
+		mode = m
+	}
+
+	g := &Game{
+		ID:        header.GameID,
+		Mode:      mode,
+		StartedAt: time.Unix(0, header.CreatedNs),
+		StartFEN:  header.StartFEN,
+		Clock:     NewClock(mode.TimeNs, mode.Increment),
+		WAL:       wal,
+		State:     GameOngoing,
+		Winner:    engine.NoColor,
+
+		lastActive: time.Now(),
+	}
+	for c := engine.Color(0); c < engine.ColorNB; c++ {
+		player := header.Player(c)
+		if g.PlayerID == "" {
+			g.PlayerID = player.ID
+		}
+		if player.BotLevel != 0 {
+			level, err := FindBotLevel(player.BotLevel)
+			if err != nil {
+				return nil, err
+			}
+			g.Bot = &Bot{Color: c, Level: level, tt: engine.NewTT(botTTMB)}
+		}
+	}
+
+	board, err := g.StartBoard()
+	if err != nil {
+		return nil, fmt.Errorf("start position: %w", err)
+	}
+	g.Board = board
+
+	last := WALEvent{WRem: mode.TimeNs, BRem: mode.TimeNs}
+	for _, e := range events {
+		if g.State != GameOngoing {
+			return nil, fmt.Errorf("event %d after the game ended", e.Seq)
+		}
+
+		switch e.Type {
+		case WALEventMove, "":
+			m, err := board.ParseUCIMove(e.MoveUCI)
+			if err != nil {
+				return nil, fmt.Errorf("event %d: %w", e.Seq, err)
+			}
+			if !board.MakeMove(m) {
+				return nil, fmt.Errorf("event %d: illegal move %s", e.Seq, e.MoveUCI)
+			}
+			g.Clock.Turn++
+			g.legalMoveCache = nil
+			g.UpdateGameState()
+		case WALEventFlag:
+			color := board.SideToMove
+			g.Clock.Flag(color)
+			g.resolveFlag(color)
+		case WALEventDraw:
+			if !g.acceptDrawClaim() {
+				return nil, fmt.Errorf("event %d: no draw to claim", e.Seq)
+			}
+		case WALEventAbandon:
+			g.State = GameAbandoned
+		}
+		g.Seq = e.Seq
+		last = e
+	}
+
+	g.Clock.White.RemainingNs = last.WRem
+	g.Clock.Black.RemainingNs = last.BRem
+
+	// The clock only runs once the opponent has made their first move
+	if g.State == GameOngoing && g.Clock.Turn > 0 {
+		g.Clock.Start(board.SideToMove)
+		g.scheduleFlag()
+	}
+	return g, nil
+}
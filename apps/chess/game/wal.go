@@ -2,18 +2,82 @@ package game
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+	"github.com/lordsonvimal/synergy/apps/chess/logger"
+)
+
+// WALDir is the directory game WAL files are written to and recovered from
+var WALDir = "."
+
+// WALArchiveDir is where the WALs of games evicted from memory are moved,
+// out of recovery's way. "" means an "archive" directory inside WALDir.
+var WALArchiveDir = ""
+
+// ErrWALClosed is returned when appending to a closed WAL
+var ErrWALClosed = errors.New("wal: closed")
+
+// --------------------------
+// Durability
+// --------------------------
+
+// WALSyncPolicy decides when appended events are fsynced to disk. Every
+// event is written to the OS straight away, so only a machine crash, not
+// a process crash, can lose events that were not synced yet.
+type WALSyncPolicy int
+
+const (
+	WALSyncAlways WALSyncPolicy = iota // fsync after every event
+	WALSyncBatch                       // fsync at most WALSyncInterval after an event
+	WALSyncNone                        // leave it to the OS
+)
+
+var (
+	WALSync         = WALSyncAlways
+	WALSyncInterval = 100 * time.Millisecond
 )
 
+// WALCompactEvery is the number of events after which the file is
+// compacted into a snapshot (0 never compacts)
+var WALCompactEvery = 256
+
+// ParseWALSyncPolicy accepts "always", "batch" or "none"
+func ParseWALSyncPolicy(s string) (WALSyncPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "always":
+		return WALSyncAlways, nil
+	case "batch":
+		return WALSyncBatch, nil
+	case "none":
+		return WALSyncNone, nil
+	}
+	return WALSyncAlways, fmt.Errorf("unknown WAL sync policy %q", s)
+}
+
 // --------------------------
 // WAL Event
 // --------------------------
 
+// WAL event types. Events written before types existed have an empty
+// type and are moves.
+const (
+	WALEventMove    = "move"
+	WALEventFlag    = "flag"
+	WALEventDraw    = "draw"    // a draw claim was accepted
+	WALEventAbandon = "abandon" // the game was left idle
+)
+
 type WALEvent struct {
 	Seq       uint64 `json:"seq"`
+	Type      string `json:"type,omitempty"`
 	MoveUCI   string `json:"move_uci"`
 	ServerNs  int64  `json:"server_ns"`
 	LagCompNs int64  `json:"lag_comp_ns"`
@@ -21,56 +85,279 @@ type WALEvent struct {
 	BRem      int64  `json:"b_rem"`
 }
 
+// --------------------------
+// WAL Header
+// --------------------------
+
+// WALHeader is the first record of a WAL: everything needed to set the
+// game up again before replaying its events
+type WALHeader struct {
+	Version   int       `json:"wal"`
+	GameID    string    `json:"game_id"`
+	Mode      string    `json:"mode"`
+	StartFEN  string    `json:"start_fen,omitempty"` // "" for the standard start
+	White     WALPlayer `json:"white"`
+	Black     WALPlayer `json:"black"`
+	CreatedNs int64     `json:"created_ns"`
+}
+
+// WALPlayer describes who plays one side
+type WALPlayer struct {
+	ID       string `json:"id,omitempty"`        // a human's player ID, if known
+	BotLevel int    `json:"bot_level,omitempty"` // engine strength, 0 for a human
+}
+
+// Player returns the side's player record
+func (h *WALHeader) Player(c engine.Color) *WALPlayer {
+	if c == engine.White {
+		return &h.White
+	}
+	return &h.Black
+}
+
 // --------------------------
 // WAL
 // --------------------------
 
 type WAL struct {
 	mu       sync.Mutex
+	header   WALHeader
 	events   []WALEvent
 	file     *os.File
 	writer   *bufio.Writer
 	filePath string
+
+	sinceSnapshot int         // events written after the header or snapshot
+	syncTimer     *time.Timer // pending batched fsync
+	truncated     int64       // bytes of torn tail dropped when opened
 }
 
 // --------------------------
 // Create WAL: always in-memory + file
 // --------------------------
 
-func NewWAL(filePath string) (*WAL, error) {
-	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+// NewWAL creates a WAL file starting with the header
+func NewWAL(filePath string, h WALHeader) (*WAL, error) {
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, err
 	}
 
-	return &WAL{
+	h.Version = walVersion
+	w := &WAL{
+		header:   h,
 		events:   []WALEvent{},
 		file:     f,
 		writer:   bufio.NewWriter(f),
 		filePath: filePath,
-	}, nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.write(recordHeader, h); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewMemoryWAL returns a WAL holding the given events with no file behind
+// it, for games loaded from elsewhere. Appends fail with ErrWALClosed.
+func NewMemoryWAL(h WALHeader, events []WALEvent) *WAL {
+	h.Version = walVersion
+	return &WAL{header: h, events: events}
+}
+
+// OpenWAL opens an existing WAL file for appending, with its events loaded
+// into memory. A torn record at the end, left by a crash mid-write, is
+// truncated away; corruption anywhere else is an error. Version 1 files
+// are rewritten in the current format.
+func OpenWAL(filePath string) (*WAL, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := decodeWAL(data)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WAL{
+		header:        contents.header,
+		events:        contents.events,
+		filePath:      filePath,
+		sinceSnapshot: contents.sinceSnapshot,
+		truncated:     int64(len(data) - contents.valid),
+	}
+	if contents.legacy {
+		w.header.GameID = walGameID(filePath)
+		w.header.Version = walVersion
+		if err := w.rewrite(); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+
+	if w.truncated > 0 {
+		if err := os.Truncate(filePath, int64(contents.valid)); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.reopen(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// walPath is the WAL file of the game with the given ID
+func walPath(gameID string) string {
+	return filepath.Join(WALDir, "game_"+gameID+".wal")
+}
+
+// walGameID is the ID of the game a WAL file belongs to
+func walGameID(filePath string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(filepath.Base(filePath), "game_"), ".wal")
 }
 
 // --------------------------
 // Append an event (both memory & file)
 // --------------------------
 
+// Append logs e, returning an error if it could not be written. A failed
+// write may leave a torn record behind, so it also closes the WAL rather
+// than let later records land after it.
 func (w *WAL) Append(e WALEvent) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	// In-memory
+	if w.file == nil {
+		return ErrWALClosed
+	}
+
+	// File first, so memory only holds what was logged
+	if err := w.write(recordEvent, e); err != nil {
+		w.close()
+		return err
+	}
 	w.events = append(w.events, e)
 
-	// File
-	data, err := json.Marshal(e)
+	w.sinceSnapshot++
+	if WALCompactEvery > 0 && w.sinceSnapshot >= WALCompactEvery {
+		// The event is logged either way; compaction is retried next time
+		if err := w.rewrite(); err != nil {
+			logger.Warn(context.Background()).Err(err).Str("wal", w.filePath).Msg("Failed to compact WAL")
+		}
+	}
+	return nil
+}
+
+// Closed reports whether appends fail, because the WAL was closed, failed
+// to write or never had a file
+func (w *WAL) Closed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file == nil
+}
+
+// write appends one record and syncs it as the policy asks. Caller must
+// hold w.mu.
+func (w *WAL) write(kind byte, payload any) error {
+	if err := writeRecord(w.writer, kind, payload); err != nil {
+		return err
+	}
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+
+	switch WALSync {
+	case WALSyncAlways:
+		return w.file.Sync()
+	case WALSyncBatch:
+		if w.syncTimer == nil {
+			w.syncTimer = time.AfterFunc(WALSyncInterval, w.batchSync)
+		}
+	}
+	return nil
+}
+
+func (w *WAL) batchSync() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.syncTimer = nil
+	if w.file != nil {
+		w.file.Sync()
+	}
+}
+
+// --------------------------
+// Compaction
+// --------------------------
+
+// rewrite replaces the file with the header, a snapshot folding in every
+// move so far and any later events, then carries on appending to it. The
+// new file is synced and renamed over the old one, so a crash leaves one
+// or the other intact.
+func (w *WAL) rewrite() error {
+	tmpPath := w.filePath + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
 	}
-	if _, err := w.writer.Write(append(data, '\n')); err != nil {
+
+	snap, rest := newWALSnapshot(w.events)
+	bw := bufio.NewWriter(f)
+	err = writeRecord(bw, recordHeader, w.header)
+	if err == nil && len(snap.Moves) > 0 {
+		err = writeRecord(bw, recordSnapshot, snap)
+	}
+	for _, e := range rest {
+		if err == nil {
+			err = writeRecord(bw, recordEvent, e)
+		}
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, w.filePath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
 		return err
 	}
-	return w.writer.Flush()
+	syncDir(filepath.Dir(w.filePath))
+
+	w.sinceSnapshot = len(rest)
+	if w.file != nil {
+		w.file.Close()
+		w.file, w.writer = nil, nil
+	}
+	return w.reopen()
+}
+
+// reopen opens the file for appending
+func (w *WAL) reopen() error {
+	f, err := os.OpenFile(w.filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	w.writer = bufio.NewWriter(f)
+	return nil
+}
+
+// syncDir makes a rename in dir durable. Not every platform can sync a
+// directory, so failures are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
 }
 
 // --------------------------
@@ -81,23 +368,15 @@ func (w *WAL) LoadFromFile() ([]WALEvent, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	events := []WALEvent{}
-	f, err := os.Open(w.filePath)
+	data, err := os.ReadFile(w.filePath)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		var e WALEvent
-		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
-			continue // skip invalid lines
-		}
-		events = append(events, e)
+	contents, err := decodeWAL(data)
+	if err != nil {
+		return nil, err
 	}
-
-	return events, scanner.Err()
+	return contents.events, nil
 }
 
 // --------------------------
@@ -113,19 +392,76 @@ func (w *WAL) LoadFromMemory() []WALEvent {
 	return eventsCopy
 }
 
+// Header returns the WAL's header record
+func (w *WAL) Header() WALHeader {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.header
+}
+
+// Truncated returns the bytes of torn tail dropped when the WAL was opened
+func (w *WAL) Truncated() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.truncated
+}
+
 // --------------------------
 // Close WAL
 // --------------------------
 
+// Close flushes, syncs and closes the file. The events stay readable from
+// memory; further appends fail with ErrWALClosed.
 func (w *WAL) Close() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	return w.close()
+}
+
+// close is Close for callers holding w.mu
+func (w *WAL) close() error {
+	if w.syncTimer != nil {
+		w.syncTimer.Stop()
+		w.syncTimer = nil
+	}
 	if w.writer != nil {
 		w.writer.Flush()
+		w.writer = nil
 	}
 	if w.file != nil {
-		return w.file.Close()
+		w.file.Sync()
+		err := w.file.Close()
+		w.file = nil
+		return err
+	}
+	return nil
+}
+
+// --------------------------
+// Archive WAL
+// --------------------------
+
+// Archive closes the WAL and moves its file to WALArchiveDir, so the game
+// is no longer recovered on startup. A WAL without a file is only closed.
+func (w *WAL) Archive() error {
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if w.filePath == "" {
+		return nil
+	}
+
+	dir := WALArchiveDir
+	if dir == "" {
+		dir = filepath.Join(WALDir, "archive")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(w.filePath, filepath.Join(dir, filepath.Base(w.filePath))); err != nil {
+		return err
 	}
+	syncDir(filepath.Dir(w.filePath))
 	return nil
 }
 
@@ -136,6 +472,7 @@ func (w *WAL) Close() error {
 func NewWALEvent(seq uint64, moveUCI string, wRem, bRem int64, lagComp int64) WALEvent {
 	return WALEvent{
 		Seq:       seq,
+		Type:      WALEventMove,
 		MoveUCI:   moveUCI,
 		ServerNs:  time.Now().UnixNano(),
 		LagCompNs: lagComp,
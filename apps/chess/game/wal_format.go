@@ -0,0 +1,220 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+)
+
+// --------------------------
+// WAL file format
+// --------------------------
+
+// A version 2 WAL holds one record per line:
+//
+//	<kind> <CRC-32C of the JSON, 8 hex digits> <JSON>
+//
+// The header (H) comes first, then at most one snapshot (S) folding in the
+// moves played before it, then events (E). Version 1 files are bare JSON
+// move event lines, with no header.
+const walVersion = 2
+
+const (
+	recordHeader   = 'H'
+	recordSnapshot = 'S'
+	recordEvent    = 'E'
+)
+
+// ErrWALCorrupt is wrapped by errors for records that fail their checksum
+// or do not parse, anywhere but at the end of the file
+var ErrWALCorrupt = errors.New("wal: corrupt record")
+
+var walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+func writeRecord(w *bufio.Writer, kind byte, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%c %08x ", kind, crc32.Checksum(data, walCRCTable))
+	w.Write(data)
+	return w.WriteByte('\n')
+}
+
+// parseRecord splits a record line into its kind and checked JSON payload
+func parseRecord(line []byte) (byte, []byte, error) {
+	if len(line) < 11 || line[1] != ' ' || line[10] != ' ' {
+		return 0, nil, errors.New("malformed record")
+	}
+	sum, err := strconv.ParseUint(string(line[2:10]), 16, 32)
+	if err != nil {
+		return 0, nil, errors.New("malformed checksum")
+	}
+	payload := line[11:]
+	if crc32.Checksum(payload, walCRCTable) != uint32(sum) {
+		return 0, nil, errors.New("checksum mismatch")
+	}
+	return line[0], payload, nil
+}
+
+// --------------------------
+// Snapshot
+// --------------------------
+
+// walSnapshot folds consecutive move events into one record: their moves
+// and the clocks after each. Per-event timestamps are dropped.
+type walSnapshot struct {
+	Seq      uint64   `json:"seq"` // of the last move folded in
+	Moves    []string `json:"moves"`
+	WRem     []int64  `json:"w_rem"`
+	BRem     []int64  `json:"b_rem"`
+	ServerNs int64    `json:"server_ns"` // of the last move folded in
+}
+
+// newWALSnapshot folds the leading move events in, returning the events
+// after them (the flag or draw ending the game, if any)
+func newWALSnapshot(events []WALEvent) (walSnapshot, []WALEvent) {
+	var snap walSnapshot
+	n := 0
+	for ; n < len(events); n++ {
+		e := events[n]
+		if e.Type != WALEventMove && e.Type != "" {
+			break
+		}
+		snap.Seq = e.Seq
+		snap.Moves = append(snap.Moves, e.MoveUCI)
+		snap.WRem = append(snap.WRem, e.WRem)
+		snap.BRem = append(snap.BRem, e.BRem)
+		snap.ServerNs = e.ServerNs
+	}
+	return snap, events[n:]
+}
+
+// events unfolds the snapshot back into move events
+func (s walSnapshot) events() ([]WALEvent, error) {
+	if len(s.WRem) != len(s.Moves) || len(s.BRem) != len(s.Moves) || s.Seq < uint64(len(s.Moves)) {
+		return nil, errors.New("inconsistent snapshot")
+	}
+	first := s.Seq - uint64(len(s.Moves)) + 1
+	events := make([]WALEvent, len(s.Moves))
+	for i, m := range s.Moves {
+		events[i] = WALEvent{
+			Seq:     first + uint64(i),
+			Type:    WALEventMove,
+			MoveUCI: m,
+			WRem:    s.WRem[i],
+			BRem:    s.BRem[i],
+		}
+	}
+	if len(events) > 0 {
+		events[len(events)-1].ServerNs = s.ServerNs
+	}
+	return events, nil
+}
+
+// --------------------------
+// Decoding
+// --------------------------
+
+type walContents struct {
+	header        WALHeader
+	events        []WALEvent
+	sinceSnapshot int
+	valid         int  // bytes up to the end of the last good record
+	legacy        bool // a version 1 file
+}
+
+// decodeWAL reads a whole WAL file. A bad last record is taken to be a
+// torn write and left out of valid; a bad record before others is an error.
+func decodeWAL(data []byte) (walContents, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return walContents{}, errors.New("wal: empty file")
+	}
+	if data[0] == '{' {
+		return decodeWALv1(data), nil
+	}
+
+	var c walContents
+	for n, off := 1, 0; off < len(data); n++ {
+		end := bytes.IndexByte(data[off:], '\n')
+		last := end < 0 || off+end+1 == len(data)
+		if end < 0 {
+			end = len(data) - off
+		}
+		line := data[off : off+end]
+
+		err := c.decodeRecord(n, line)
+		if err != nil {
+			if last && n > 1 {
+				break // torn tail
+			}
+			return walContents{}, fmt.Errorf("%w: line %d: %v", ErrWALCorrupt, n, err)
+		}
+		off += end + 1
+		c.valid = min(off, len(data))
+	}
+	return c, nil
+}
+
+func (c *walContents) decodeRecord(n int, line []byte) error {
+	kind, payload, err := parseRecord(line)
+	if err != nil {
+		return err
+	}
+	if (kind == recordHeader) != (n == 1) {
+		return errors.New("header must be the first record")
+	}
+
+	switch kind {
+	case recordHeader:
+		if err := json.Unmarshal(payload, &c.header); err != nil {
+			return err
+		}
+		if c.header.Version != walVersion {
+			return fmt.Errorf("unsupported version %d", c.header.Version)
+		}
+	case recordSnapshot:
+		if len(c.events) > 0 {
+			return errors.New("snapshot after events")
+		}
+		var snap walSnapshot
+		if err := json.Unmarshal(payload, &snap); err != nil {
+			return err
+		}
+		events, err := snap.events()
+		if err != nil {
+			return err
+		}
+		c.events = events
+	case recordEvent:
+		var e WALEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return err
+		}
+		c.events = append(c.events, e)
+		c.sinceSnapshot++
+	default:
+		return fmt.Errorf("unknown record kind %q", kind)
+	}
+	return nil
+}
+
+// decodeWALv1 reads a version 1 file, skipping lines that do not parse as
+// that format always did. Having no header, it leaves the header's mode
+// empty.
+func decodeWALv1(data []byte) walContents {
+	c := walContents{legacy: true, valid: len(data)}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		var e WALEvent
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		c.events = append(c.events, e)
+	}
+	return c
+}
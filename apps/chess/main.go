@@ -2,16 +2,23 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-contrib/requestid"
 	"github.com/gin-gonic/gin"
+	"github.com/lordsonvimal/synergy/apps/chess/analysis"
 	"github.com/lordsonvimal/synergy/apps/chess/config"
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+	"github.com/lordsonvimal/synergy/apps/chess/game"
 	"github.com/lordsonvimal/synergy/apps/chess/logger"
+	"github.com/lordsonvimal/synergy/apps/chess/review"
 	"github.com/lordsonvimal/synergy/apps/chess/server"
 	"github.com/lordsonvimal/synergy/apps/chess/store"
 	"github.com/rs/zerolog/log"
@@ -34,14 +41,112 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	if n, err := strconv.Atoi(config.GetEnv("SEARCH_THREADS", "")); err == nil && n > 0 {
+		game.BotThreads = n
+		analysis.Threads = n
+	}
+	logger.Info(ctx).Int("SEARCH_THREADS", game.BotThreads).Msg("Engine search threads")
+
+	if n, err := strconv.Atoi(config.GetEnv("ANALYSIS_MAX_THREADS", "")); err == nil && n > 0 {
+		analysis.MaxThreads = n
+	}
+
+	if n, err := strconv.Atoi(config.GetEnv("REVIEW_DEPTH", "")); err == nil && n > 0 {
+		review.Depth = n
+	}
+
+	if path := config.GetEnv("BOOK_FILE", ""); path != "" {
+		book, err := engine.LoadBook(path)
+		if err != nil {
+			logger.Fatal(ctx).Err(err).Str("BOOK_FILE", path).Msg("Failed to load opening book")
+		}
+		book.MaxPly = 16
+		if n, err := strconv.Atoi(config.GetEnv("BOOK_DEPTH", "")); err == nil && n >= 0 {
+			book.MaxPly = n
+		}
+		game.BotBook = book
+		logger.Info(ctx).Str("BOOK_FILE", path).Int("entries", book.Len()).Int("BOOK_DEPTH", book.MaxPly).Msg("Opening book loaded")
+	}
+
+	if paths := config.GetEnv("SYZYGY_PATH", ""); paths != "" {
+		tb, err := engine.LoadTablebase(paths)
+		if err != nil {
+			logger.Fatal(ctx).Err(err).Str("SYZYGY_PATH", paths).Msg("Failed to load tablebases")
+		}
+		game.BotTB = tb
+		analysis.TB = tb
+		logger.Info(ctx).Str("SYZYGY_PATH", paths).Int("tables", tb.Len()).Int("pieces", tb.MaxPieces).Msg("Syzygy tablebases loaded")
+	}
+
+	if origins := config.GetEnv("WS_ALLOWED_ORIGINS", ""); origins != "" {
+		server.AllowedOrigins = strings.Split(origins, ",")
+	}
+
 	router := gin.New()
 
-	gameStore := store.NewGameStore()
+	var gameStore store.GameRepository = store.NewGameStore()
+	if path := config.GetEnv("DB_PATH", ""); path != "" {
+		db, err := store.NewSQLiteGameStore(path)
+		if err != nil {
+			logger.Fatal(ctx).Err(err).Str("DB_PATH", path).Msg("Failed to open game database")
+		}
+		defer db.Close()
+		gameStore = db
+		logger.Info(ctx).Str("DB_PATH", path).Msg("Games stored in SQLite")
+	}
+	maxAnalyses := 64
+	if n, err := strconv.Atoi(config.GetEnv("ANALYSIS_MAX_SESSIONS", "")); err == nil && n > 0 {
+		maxAnalyses = n
+	}
+	analysisStore := store.NewAnalysisStore(maxAnalyses)
+	reviewStore := store.NewReviewStore()
+
+	game.WALDir = config.GetEnv("WAL_DIR", game.WALDir)
+	if err := os.MkdirAll(game.WALDir, 0755); err != nil {
+		logger.Fatal(ctx).Err(err).Str("WAL_DIR", game.WALDir).Msg("Failed to create WAL directory")
+	}
+	if s := config.GetEnv("WAL_SYNC", ""); s != "" {
+		policy, err := game.ParseWALSyncPolicy(s)
+		if err != nil {
+			logger.Fatal(ctx).Err(err).Msg("Invalid WAL_SYNC")
+		}
+		game.WALSync = policy
+	}
+
+	games, err := game.RecoverGames(game.WALDir)
+	if err != nil {
+		logger.Warn(ctx).Err(err).Msg("Some games could not be recovered")
+	}
+	for _, g := range games {
+		if n := g.WAL.Truncated(); n > 0 {
+			logger.Warn(ctx).Str("game", g.ID).Int64("bytes", n).Msg("Truncated torn WAL tail")
+		}
+	}
+	server.RestoreGames(games, gameStore, reviewStore)
+	logger.Info(ctx).Int("games", len(games)).Str("dir", game.WALDir).Msg("Games recovered from WAL")
+
+	game.WALArchiveDir = config.GetEnv("WAL_ARCHIVE_DIR", game.WALArchiveDir)
+	janitor := &store.Janitor{
+		Games:           gameStore,
+		Reviews:         reviewStore,
+		Analyses:        analysisStore,
+		IdleTimeout:     envDuration(ctx, "GAME_IDLE_TIMEOUT", 30*time.Minute),
+		FinishedTTL:     envDuration(ctx, "GAME_FINISHED_TTL", time.Hour),
+		AnalysisTimeout: envDuration(ctx, "ANALYSIS_IDLE_TIMEOUT", 15*time.Minute),
+		Interval:        time.Minute,
+	}
+	janitorCtx, stopJanitor := context.WithCancel(ctx)
+	defer stopJanitor()
+	go janitor.Run(janitorCtx)
+	store.PublishGameCounts(gameStore)
 
 	router.Use(requestid.New())                                        // Add this for correlation IDs
 	router.Use(logger.RedactedStructuredLogger(logger.GlobalLogger())) // Structured logging with token redaction (access_token, auth_token, etc.)
 	router.Use(gin.Recovery())                                         // Use default recovery for panic logging/handling
 	router.Use(store.StoreContext(gameStore))                          // Add gameStore to context
+	router.Use(store.PlayerContext())                                  // Add the visitor's player ID to context
+	router.Use(store.AnalysisContext(analysisStore))                   // Add analysisStore to context
+	router.Use(store.ReviewContext(reviewStore))                       // Add reviewStore to context
 
 	router.Static("/static", "./dist")
 	router.StaticFile("/favicon.ico", "assets/favicon.ico")
@@ -62,6 +167,25 @@ func main() {
 		}
 	}()
 
+	// Metrics expose process internals, so they get their own listener,
+	// local only unless METRICS_ADDR says otherwise
+	var metricsSrv *http.Server
+	if addr := config.GetEnv("METRICS_ADDR", "127.0.0.1:6060"); addr != "off" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+		metricsSrv = &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error(ctx).Err(err).Str("METRICS_ADDR", addr).Msg("Metrics listen error")
+			}
+		}()
+		logger.Info(ctx).Str("METRICS_ADDR", addr).Msg("Metrics served at /debug/vars")
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -71,9 +195,25 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if metricsSrv != nil {
+		metricsSrv.Shutdown(shutdownCtx)
+	}
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Fatal(ctx).Err(err).Msg("Server forced to shutdown")
 	}
 
 	logger.Info(ctx).Msg("Server exiting gracefully.")
 }
+
+// envDuration reads a duration such as "30m" from the environment
+func envDuration(ctx context.Context, key string, fallback time.Duration) time.Duration {
+	s := config.GetEnv(key, "")
+	if s == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		logger.Fatal(ctx).Err(err).Str(key, s).Msg("Invalid duration")
+	}
+	return d
+}
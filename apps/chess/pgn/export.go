@@ -0,0 +1,170 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+	"github.com/lordsonvimal/synergy/apps/chess/game"
+)
+
+// Export format wraps movetext at 80 columns
+const maxLineLen = 79
+
+// --------------------------
+// Export
+// --------------------------
+
+// Export renders a game as PGN text
+func Export(g *game.Game) (string, error) {
+	var sb strings.Builder
+	if err := Write(&sb, g); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Write writes a game in PGN export format: the seven-tag roster plus
+// TimeControl/Termination, SAN movetext with [%clk] comments taken from
+// the WAL, and the result
+func Write(w io.Writer, g *game.Game) error {
+	state, winner := g.Outcome()
+	result := ResultString(state, winner)
+
+	event := g.Mode.Name
+	if event == "" {
+		event = "Casual game"
+	}
+
+	tags := []Tag{
+		{"Event", event},
+		{"Site", "?"},
+		{"Date", g.StartedAt.Format("2006.01.02")},
+		{"Round", "-"},
+		{"White", "?"},
+		{"Black", "?"},
+		{"Result", result},
+		{"TimeControl", timeControl(g.Mode)},
+		{"Termination", termination(state)},
+	}
+	if g.Mode.Variant != "" && g.Mode.Variant != game.VariantStandard {
+		tags = append(tags, Tag{"Variant", g.Mode.Variant})
+	}
+	if g.StartFEN != "" {
+		tags = append(tags, Tag{"SetUp", "1"}, Tag{"FEN", g.StartFEN})
+	}
+
+	var sb strings.Builder
+	for _, t := range tags {
+		fmt.Fprintf(&sb, "[%s %s]\n", t.Name, quote(t.Value))
+	}
+	sb.WriteByte('\n')
+
+	mt, err := movetext(g)
+	if err != nil {
+		return err
+	}
+	mt.word(result)
+	sb.WriteString(mt.String())
+	sb.WriteString("\n\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// movetext replays the WAL moves from the start position and renders them
+// as SAN
+func movetext(g *game.Game) (*lineWriter, error) {
+	mt := &lineWriter{}
+	board, err := g.StartBoard()
+	if err != nil {
+		return nil, fmt.Errorf("pgn: start position: %w", err)
+	}
+	afterComment := false
+
+	for _, e := range g.WAL.LoadFromMemory() {
+		if e.Type != "" && e.Type != game.WALEventMove {
+			continue
+		}
+		m, err := board.ParseUCIMove(e.MoveUCI)
+		if err != nil {
+			return nil, fmt.Errorf("pgn: WAL event %d: %w", e.Seq, err)
+		}
+
+		// Move numbers stay on the same line as their move
+		color := board.SideToMove
+		number := ""
+		if color == engine.White {
+			number = strconv.Itoa(int(board.FullMoveNumber)) + ". "
+		} else if afterComment {
+			number = strconv.Itoa(int(board.FullMoveNumber)) + "... "
+		}
+
+		san := board.SAN(m)
+		board.MakeMove(m)
+		mt.word(number + san)
+
+		remaining := e.WRem
+		if color == engine.Black {
+			remaining = e.BRem
+		}
+		mt.word("{ [%clk " + formatClock(remaining) + "] }")
+		afterComment = true
+	}
+
+	return mt, nil
+}
+
+// --------------------------
+// Helpers
+// --------------------------
+
+// lineWriter joins words with spaces, wrapping lines at maxLineLen
+type lineWriter struct {
+	sb      strings.Builder
+	lineLen int
+}
+
+func (lw *lineWriter) word(s string) {
+	if lw.lineLen > 0 {
+		if lw.lineLen+1+len(s) > maxLineLen {
+			lw.sb.WriteByte('\n')
+			lw.lineLen = 0
+		} else {
+			lw.sb.WriteByte(' ')
+			lw.lineLen++
+		}
+	}
+	lw.sb.WriteString(s)
+	lw.lineLen += len(s)
+}
+
+func (lw *lineWriter) String() string {
+	return lw.sb.String()
+}
+
+// formatClock renders nanoseconds as H:MM:SS
+func formatClock(ns int64) string {
+	if ns < 0 {
+		ns = 0
+	}
+	secs := ns / 1_000_000_000
+	return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+}
+
+// timeControl renders a game mode as "base+increment" in seconds
+func timeControl(mode game.GameMode) string {
+	if mode.TimeNs <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d+%d", mode.TimeNs/1_000_000_000, mode.Increment/1_000_000_000)
+}
+
+// quote escapes a tag value as a PGN string token
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
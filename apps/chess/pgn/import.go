@@ -0,0 +1,420 @@
+package pgn
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+)
+
+// --------------------------
+// Imported game
+// --------------------------
+
+// Game is a game read from PGN
+type Game struct {
+	Tags   []Tag
+	Moves  []engine.Move
+	Clocks []int64 // remaining ns after each move from [%clk] comments, -1 if absent
+	Result string
+	Board  *engine.Board // final position; MoveStack holds the moves
+}
+
+// Tag returns the value of the named tag, or "" if absent
+func (g *Game) Tag(name string) string {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// ParseError locates a problem in PGN input
+type ParseError struct {
+	Line int
+	Col  int
+	Msg  string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("pgn: line %d, col %d: %s: %v", e.Line, e.Col, e.Msg, e.Err)
+	}
+	return fmt.Sprintf("pgn: line %d, col %d: %s", e.Line, e.Col, e.Msg)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// --------------------------
+// Import
+// --------------------------
+
+// Import reads every game from a PGN file, resolving SAN moves against the
+// legal moves of each position. A FEN tag sets the starting position and a
+// Variant tag the rules.
+func Import(r io.Reader) ([]*Game, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{lex: &lexer{src: string(data), line: 1, col: 1}}
+	var games []*Game
+	for {
+		g, err := p.game()
+		if err != nil {
+			return nil, err
+		}
+		if g == nil {
+			return games, nil
+		}
+		games = append(games, g)
+	}
+}
+
+// --------------------------
+// Parser
+// --------------------------
+
+type parser struct {
+	lex  *lexer
+	peek *token
+}
+
+func (p *parser) next() (token, error) {
+	if p.peek != nil {
+		t := *p.peek
+		p.peek = nil
+		return t, nil
+	}
+	return p.lex.next()
+}
+
+func (p *parser) unread(t token) {
+	p.peek = &t
+}
+
+// game parses one game, returning nil at end of input
+func (p *parser) game() (*Game, error) {
+	g := &Game{Result: ResultUnknown}
+
+	// 1. Tag pairs
+	for {
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if t.kind != tokTag {
+			p.unread(t)
+			break
+		}
+		g.Tags = append(g.Tags, Tag{Name: t.name, Value: t.text})
+	}
+
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.kind == tokEOF && len(g.Tags) == 0 {
+		return nil, nil
+	}
+	p.unread(t)
+
+	// 2. Starting position
+	g.Board = engine.NewBoard()
+	if fen := g.Tag("FEN"); fen != "" {
+		b, err := engine.ParseFEN(fen)
+		if err != nil {
+			return nil, &ParseError{Line: t.line, Col: t.col, Msg: "invalid FEN tag", Err: err}
+		}
+		g.Board = b
+	}
+	if variant := g.Tag("Variant"); strings.EqualFold(variant, "chess960") {
+		g.Board.Chess960 = true
+	} else if v, err := engine.ParseVariant(variant); err == nil {
+		g.Board.SetVariant(v)
+	}
+
+	// 3. Movetext
+	depth := 0 // variation nesting, moves inside are skipped
+	for {
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t.kind {
+		case tokEOF:
+			return g, nil
+
+		case tokTag:
+			// Next game started without a result token
+			p.unread(t)
+			return g, nil
+
+		case tokOpen:
+			depth++
+
+		case tokClose:
+			if depth == 0 {
+				return nil, &ParseError{Line: t.line, Col: t.col, Msg: "unbalanced ')'"}
+			}
+			depth--
+
+		case tokComment:
+			if depth == 0 && len(g.Clocks) > 0 {
+				if ns, ok := parseClockComment(t.text); ok {
+					g.Clocks[len(g.Clocks)-1] = ns
+				}
+			}
+
+		case tokNAG:
+			// Annotation glyphs carry no move information
+
+		case tokSymbol:
+			if isResult(t.text) {
+				if depth != 0 {
+					return nil, &ParseError{Line: t.line, Col: t.col, Msg: "result inside a variation"}
+				}
+				g.Result = t.text
+				return g, nil
+			}
+			if depth > 0 {
+				continue
+			}
+
+			san := stripMoveNumber(t.text)
+			if san == "" {
+				continue
+			}
+			m, err := g.Board.ParseSAN(san)
+			if err != nil {
+				return nil, &ParseError{Line: t.line, Col: t.col, Msg: fmt.Sprintf("illegal move %q", san), Err: err}
+			}
+			g.Board.MakeMove(m)
+			g.Moves = append(g.Moves, m)
+			g.Clocks = append(g.Clocks, -1)
+		}
+	}
+}
+
+func isResult(s string) bool {
+	return s == ResultWhiteWins || s == ResultBlackWins || s == ResultDraw || s == ResultUnknown
+}
+
+// stripMoveNumber removes a leading move number ("12.", "12...") from a
+// symbol, returning what remains (possibly empty)
+func stripMoveNumber(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i == 0 || i == len(s) || s[i] != '.' {
+		return s // not a move number ("0-0" style castling falls through here)
+	}
+	return strings.TrimLeft(s[i:], ".")
+}
+
+// parseClockComment extracts [%clk H:MM:SS(.f)] from a comment
+func parseClockComment(c string) (int64, bool) {
+	i := strings.Index(c, "[%clk ")
+	if i < 0 {
+		return 0, false
+	}
+	rest := c[i+len("[%clk "):]
+	j := strings.IndexByte(rest, ']')
+	if j < 0 {
+		return 0, false
+	}
+
+	parts := strings.Split(strings.TrimSpace(rest[:j]), ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	s, err3 := strconv.ParseFloat(parts[2], 64)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return 0, false
+	}
+	return int64(h)*3600_000_000_000 + int64(m)*60_000_000_000 + int64(s*1e9), true
+}
+
+// --------------------------
+// Lexer
+// --------------------------
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokTag
+	tokSymbol
+	tokComment
+	tokNAG
+	tokOpen
+	tokClose
+)
+
+type token struct {
+	kind      tokenKind
+	name      string // tag name
+	text      string
+	line, col int
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (l *lexer) errorf(line, col int, format string, args ...any) error {
+	return &ParseError{Line: line, Col: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) advance() byte {
+	ch := l.src[l.pos]
+	l.pos++
+	if ch == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return ch
+}
+
+func (l *lexer) skipLine() {
+	for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+		l.advance()
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		line, col := l.line, l.col
+
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			l.advance()
+
+		case ch == '%' && col == 1:
+			// Escape mechanism: ignore the whole line
+			l.skipLine()
+
+		case ch == ';':
+			l.skipLine()
+
+		case ch == '{':
+			l.advance()
+			start := l.pos
+			for l.pos < len(l.src) && l.src[l.pos] != '}' {
+				l.advance()
+			}
+			if l.pos >= len(l.src) {
+				return token{}, l.errorf(line, col, "unterminated comment")
+			}
+			text := l.src[start:l.pos]
+			l.advance()
+			return token{kind: tokComment, text: text, line: line, col: col}, nil
+
+		case ch == '[':
+			return l.tag(line, col)
+
+		case ch == '(':
+			l.advance()
+			return token{kind: tokOpen, line: line, col: col}, nil
+
+		case ch == ')':
+			l.advance()
+			return token{kind: tokClose, line: line, col: col}, nil
+
+		case ch == '$':
+			l.advance()
+			start := l.pos
+			for l.pos < len(l.src) && l.src[l.pos] >= '0' && l.src[l.pos] <= '9' {
+				l.advance()
+			}
+			return token{kind: tokNAG, text: l.src[start:l.pos], line: line, col: col}, nil
+
+		case isSymbolChar(ch):
+			start := l.pos
+			for l.pos < len(l.src) && isSymbolChar(l.src[l.pos]) {
+				l.advance()
+			}
+			return token{kind: tokSymbol, text: l.src[start:l.pos], line: line, col: col}, nil
+
+		default:
+			return token{}, l.errorf(line, col, "unexpected character %q", ch)
+		}
+	}
+
+	return token{kind: tokEOF, line: l.line, col: l.col}, nil
+}
+
+// tag lexes [Name "Value"]
+func (l *lexer) tag(line, col int) (token, error) {
+	l.advance() // '['
+	l.skipSpaces()
+
+	start := l.pos
+	for l.pos < len(l.src) && isSymbolChar(l.src[l.pos]) {
+		l.advance()
+	}
+	name := l.src[start:l.pos]
+	if name == "" {
+		return token{}, l.errorf(l.line, l.col, "missing tag name")
+	}
+
+	l.skipSpaces()
+	if l.pos >= len(l.src) || l.src[l.pos] != '"' {
+		return token{}, l.errorf(l.line, l.col, "tag %s: expected quoted value", name)
+	}
+	l.advance()
+
+	var value strings.Builder
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return token{}, l.errorf(line, col, "tag %s: unterminated string", name)
+		}
+		ch := l.advance()
+		if ch == '"' {
+			break
+		}
+		if ch == '\\' && l.pos < len(l.src) {
+			ch = l.advance()
+		}
+		value.WriteByte(ch)
+	}
+
+	l.skipSpaces()
+	if l.pos >= len(l.src) || l.src[l.pos] != ']' {
+		return token{}, l.errorf(l.line, l.col, "tag %s: expected ']'", name)
+	}
+	l.advance()
+
+	return token{kind: tokTag, name: name, text: value.String(), line: line, col: col}, nil
+}
+
+func (l *lexer) skipSpaces() {
+	for l.pos < len(l.src) && (l.src[l.pos] == ' ' || l.src[l.pos] == '\t') {
+		l.advance()
+	}
+}
+
+func isSymbolChar(ch byte) bool {
+	switch {
+	case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		return true
+	}
+	return strings.IndexByte("_+#=:-/.!?*", ch) >= 0
+}
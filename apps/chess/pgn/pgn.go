@@ -0,0 +1,57 @@
+// Package pgn reads and writes games in Portable Game Notation.
+package pgn
+
+import (
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+	"github.com/lordsonvimal/synergy/apps/chess/game"
+)
+
+// Tag is a PGN tag pair, e.g. [Event "Casual game"]
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Game results as written in the Result tag and at the end of the movetext
+const (
+	ResultWhiteWins = "1-0"
+	ResultBlackWins = "0-1"
+	ResultDraw      = "1/2-1/2"
+	ResultUnknown   = "*"
+)
+
+// ResultString maps a game state to its PGN result
+func ResultString(state game.GameState, winner engine.Color) string {
+	switch state {
+	case game.GameOngoing, game.GameInvalid:
+		return ResultUnknown
+	}
+
+	switch winner {
+	case engine.White:
+		return ResultWhiteWins
+	case engine.Black:
+		return ResultBlackWins
+	}
+
+	switch state {
+	case game.GameAbandoned, game.GameDisconnected:
+		return ResultUnknown
+	}
+	return ResultDraw
+}
+
+// termination returns the value of the Termination tag for a finished game
+func termination(state game.GameState) string {
+	switch state {
+	case game.GameOngoing:
+		return "unterminated"
+	case game.GameClockFlagged:
+		return "time forfeit"
+	case game.GameAbandoned:
+		return "abandoned"
+	case game.GameDisconnected:
+		return "emergency"
+	}
+	return "normal"
+}
@@ -0,0 +1,80 @@
+package review
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+	"github.com/lordsonvimal/synergy/apps/chess/game"
+)
+
+// --------------------------
+// Review job
+// --------------------------
+
+// Job analyses one finished game in the background
+type Job struct {
+	GameID string
+
+	mu     sync.Mutex
+	done   int
+	total  int
+	report *Report
+	err    error
+
+	finished chan struct{}
+	cancel   context.CancelFunc
+}
+
+// Start launches the review of a game's WAL events, played from startFEN
+// ("" for the standard position) by the variant's rules
+func Start(gameID, startFEN string, variant engine.Variant, events []game.WALEvent) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &Job{
+		GameID:   gameID,
+		finished: make(chan struct{}),
+		cancel:   cancel,
+	}
+
+	go func() {
+		defer close(j.finished)
+		defer cancel()
+
+		report, err := Analyze(ctx, gameID, startFEN, variant, events, Depth, j.setProgress)
+
+		j.mu.Lock()
+		j.report, j.err = report, err
+		j.mu.Unlock()
+	}()
+	return j
+}
+
+func (j *Job) setProgress(done, total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.done, j.total = done, total
+}
+
+// Progress returns the positions searched so far and the total
+func (j *Job) Progress() (done, total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.done, j.total
+}
+
+// Result returns the report once the job has finished
+func (j *Job) Result() (*Report, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.report, j.err
+}
+
+// Finished is closed when the job completes, fails or is cancelled
+func (j *Job) Finished() <-chan struct{} {
+	return j.finished
+}
+
+// Cancel stops the job
+func (j *Job) Cancel() {
+	j.cancel()
+}
@@ -0,0 +1,286 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+	"github.com/lordsonvimal/synergy/apps/chess/game"
+)
+
+// --------------------------
+// Settings
+// --------------------------
+
+// Depth is the fixed search depth used at every ply
+var Depth = 6
+
+// Threads is the number of search threads per review. Reviews run in the
+// background, so by default they leave the other cores to live games.
+var Threads = 1
+
+// ttMB sizes each review's transposition table in megabytes
+const ttMB = 32
+
+// evalCap bounds scores used for centipawn loss and accuracy, so a missed
+// mate costs as much as a lost queen or two rather than 100,000 points
+const evalCap = 1000
+
+// --------------------------
+// Classification
+// --------------------------
+
+type Class int
+
+const (
+	ClassBest Class = iota
+	ClassGood
+	ClassInaccuracy
+	ClassMistake
+	ClassBlunder
+	ClassNB
+)
+
+// Centipawn loss at which a move drops into each class
+const (
+	inaccuracyLoss = 50
+	mistakeLoss    = 100
+	blunderLoss    = 300
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassBest:
+		return "Best"
+	case ClassGood:
+		return "Good"
+	case ClassInaccuracy:
+		return "Inaccuracy"
+	case ClassMistake:
+		return "Mistake"
+	case ClassBlunder:
+		return "Blunder"
+	}
+	return "Unknown"
+}
+
+func classify(loss int, best bool) Class {
+	switch {
+	case best || loss == 0:
+		return ClassBest
+	case loss >= blunderLoss:
+		return ClassBlunder
+	case loss >= mistakeLoss:
+		return ClassMistake
+	case loss >= inaccuracyLoss:
+		return ClassInaccuracy
+	}
+	return ClassGood
+}
+
+// --------------------------
+// Report
+// --------------------------
+
+// MoveReview is the verdict on one played move
+type MoveReview struct {
+	Ply      int // 1-based
+	Color    engine.Color
+	SAN      string
+	BestSAN  string // the engine's choice in the same position
+	Eval     int    // centipawns after the move, from white's point of view
+	Mate     int    // moves to mate after the move, negative when black mates
+	CPLoss   int
+	Accuracy float64
+	Class    Class
+}
+
+// PlayerSummary aggregates one side's moves
+type PlayerSummary struct {
+	Accuracy float64 // 0-100
+	ACPL     int     // average centipawn loss
+	Counts   [ClassNB]int
+}
+
+type Report struct {
+	GameID  string
+	Depth   int
+	Moves   []MoveReview
+	Players [engine.ColorNB]PlayerSummary
+	Elapsed time.Duration
+}
+
+// --------------------------
+// Analysis
+// --------------------------
+
+// evaluation is a searched position: its score for the side to move and
+// the engine's best move there
+type evaluation struct {
+	score int
+	mate  int
+	best  engine.Move
+	final bool // no legal moves; score is exact
+}
+
+// Analyze replays the game's WAL events from its start position (startFEN,
+// "" for the standard one) under the variant's rules and searches each
+// position to depth. progress, if set, is called after every position with
+// the number done and the total. It stops with ctx's error if ctx is done.
+func Analyze(ctx context.Context, gameID, startFEN string, variant engine.Variant, events []game.WALEvent, depth int, progress func(done, total int)) (*Report, error) {
+	start := time.Now()
+
+	moves, err := replay(startFEN, variant, events)
+	if err != nil {
+		return nil, err
+	}
+
+	board, err := startBoard(startFEN, variant)
+	if err != nil {
+		return nil, err
+	}
+	searcher := &engine.Searcher{TT: engine.NewTT(ttMB), Threads: Threads}
+
+	evals := make([]evaluation, len(moves)+1)
+	sans := make([]string, len(moves))
+	bestSANs := make([]string, len(moves))
+	if progress != nil {
+		progress(0, len(evals))
+	}
+	for ply := 0; ; ply++ {
+		evals[ply] = evaluate(ctx, searcher, board, depth)
+		if err := ctx.Err(); err != nil {
+			return nil, err // the search was cut short
+		}
+		if progress != nil {
+			progress(ply+1, len(evals))
+		}
+		if ply == len(moves) {
+			break
+		}
+
+		sans[ply] = board.SAN(moves[ply])
+		if !evals[ply].final {
+			bestSANs[ply] = board.SAN(evals[ply].best)
+		}
+		board.MakeMove(moves[ply])
+	}
+
+	report := &Report{GameID: gameID, Depth: depth}
+	var lossSum, accSum [engine.ColorNB]float64
+	var counts [engine.ColorNB]int
+
+	for ply, m := range moves {
+		color := engine.Color(ply % 2)
+		before, after := evals[ply], evals[ply+1]
+
+		// Both scores from the mover's point of view
+		scoreBefore := capEval(before.score)
+		scoreAfter := -capEval(after.score)
+		loss := max(scoreBefore-scoreAfter, 0)
+
+		acc := moveAccuracy(winPercent(scoreBefore), winPercent(scoreAfter))
+		class := classify(loss, m == before.best)
+
+		eval, mate := after.score, after.mate
+		if color == engine.White {
+			eval, mate = -eval, -mate // after the move black is to move
+		}
+
+		report.Moves = append(report.Moves, MoveReview{
+			Ply:      ply + 1,
+			Color:    color,
+			SAN:      sans[ply],
+			BestSAN:  bestSANs[ply],
+			Eval:     eval,
+			Mate:     mate,
+			CPLoss:   loss,
+			Accuracy: acc,
+			Class:    class,
+		})
+
+		lossSum[color] += float64(loss)
+		accSum[color] += acc
+		counts[color]++
+		report.Players[color].Counts[class]++
+	}
+
+	for c := range report.Players {
+		if counts[c] == 0 {
+			report.Players[c].Accuracy = 100
+			continue
+		}
+		report.Players[c].Accuracy = accSum[c] / float64(counts[c])
+		report.Players[c].ACPL = int(math.Round(lossSum[c] / float64(counts[c])))
+	}
+
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+// replay converts the WAL's move events to moves, checking each is legal
+func replay(startFEN string, variant engine.Variant, events []game.WALEvent) ([]engine.Move, error) {
+	board, err := startBoard(startFEN, variant)
+	if err != nil {
+		return nil, err
+	}
+	moves := []engine.Move{}
+	for _, e := range events {
+		if e.Type != game.WALEventMove && e.Type != "" {
+			continue
+		}
+		m, err := board.ParseUCIMove(e.MoveUCI)
+		if err != nil {
+			return nil, fmt.Errorf("replay seq %d: %w", e.Seq, err)
+		}
+		board.MakeMove(m)
+		moves = append(moves, m)
+	}
+	return moves, nil
+}
+
+// startBoard sets up startFEN, or the standard position if it is empty,
+// to be played by the variant's rules
+func startBoard(startFEN string, variant engine.Variant) (*engine.Board, error) {
+	b := engine.NewBoard()
+	if startFEN != "" {
+		if err := b.SetFEN(startFEN); err != nil {
+			return nil, fmt.Errorf("start position: %w", err)
+		}
+	}
+	b.SetVariant(variant)
+	return b, nil
+}
+
+// evaluate searches b to depth; finished positions are scored directly
+func evaluate(ctx context.Context, s *engine.Searcher, b *engine.Board, depth int) evaluation {
+	if len(b.LegalMoves()) == 0 {
+		if b.IsKingInCheck(b.SideToMove) || b.IsVariantLoss() {
+			return evaluation{score: -engine.MATE_SCORE, final: true}
+		}
+		return evaluation{final: true}
+	}
+
+	// Depth, not time, bounds the search; the limit only guards against
+	// a pathological position stalling the job
+	res := s.SearchContext(ctx, b.Clone(), depth, time.Minute)
+	return evaluation{score: res.Score, mate: res.Mate, best: res.BestMove}
+}
+
+func capEval(score int) int {
+	return min(max(score, -evalCap), evalCap)
+}
+
+// winPercent maps centipawns to the chance of winning, 0-100 (the lichess
+// model)
+func winPercent(cp int) float64 {
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(cp)))-1)
+}
+
+// moveAccuracy scores a move 0-100 by how much winning chance it gave up
+func moveAccuracy(winBefore, winAfter float64) float64 {
+	acc := 103.1668*math.Exp(-0.04354*(winBefore-winAfter)) - 3.1669
+	return min(max(acc, 0), 100)
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lordsonvimal/synergy/apps/chess/analysis"
+	"github.com/lordsonvimal/synergy/apps/chess/logger"
+	"github.com/lordsonvimal/synergy/apps/chess/store"
+	"github.com/lordsonvimal/synergy/apps/chess/ui/pages"
+	"github.com/lordsonvimal/synergy/apps/chess/ui/ui_store"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// NewAnalysis opens an analysis board on the posted fen (the initial
+// position when absent) and redirects to it
+func NewAnalysis(c *gin.Context) {
+	ctx := c.Request.Context()
+	repo, ok := store.GetAnalysisRepoFromContext(ctx)
+	logger.Info(ctx).Bool("repo found", ok).Msg("Handler: NewAnalysis")
+	if !ok {
+		return
+	}
+
+	s, err := analysis.NewSession(c.PostForm("fen"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := repo.Add(s); err != nil {
+		logger.Warn(ctx).Err(err).Msg("Analysis board refused")
+		c.String(http.StatusServiceUnavailable, "Too many analysis boards are open, try again later")
+		return
+	}
+	s.Start()
+
+	c.Redirect(http.StatusSeeOther, "/analysis/"+s.ID)
+}
+
+func ShowAnalysis(c *gin.Context) {
+	s, ok := getAnalysis(c)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	Render(c, http.StatusOK, pages.AnalysisPage(s.Snapshot()))
+}
+
+// getAnalysis looks up the session named by the :analysisID parameter
+func getAnalysis(c *gin.Context) (*analysis.Session, bool) {
+	ctx := c.Request.Context()
+	repo, ok := store.GetAnalysisRepoFromContext(ctx)
+	if !ok {
+		logger.Error(ctx).Msg("Analysis repo missing")
+		return nil, false
+	}
+
+	id, ok := c.Params.Get("analysisID")
+	if !ok {
+		logger.Error(ctx).Str("analysisID found", id).Msg("AnalysisID")
+		return nil, false
+	}
+
+	return repo.Get(id)
+}
+
+func AnalysisSelectSquare(c *gin.Context) {
+	ctx := c.Request.Context()
+	s, ok := getAnalysis(c)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	squareUInt64, err := strconv.ParseUint(c.Param("square"), 10, 8)
+	if err != nil || squareUInt64 > 63 {
+		logger.Info(ctx).Str("square", c.Param("square")).Msg("Invalid Square")
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	square := uint8(squareUInt64)
+
+	signals := ui_store.NewAnalysisSignals()
+	datastar.ReadSignals(c.Request, signals)
+
+	if !s.MoveTo(square, signals.PromotionPiece()) {
+		s.SelectSquare(square)
+	}
+
+	if err := broadcastAnalysis(c, s.Snapshot()); err != nil {
+		logger.Error(ctx).Err(err).Msg("Failed to broadcast analysis board")
+	}
+}
+
+// AnalysisLoadFEN replaces the position with the one in the fen signal
+func AnalysisLoadFEN(c *gin.Context) {
+	ctx := c.Request.Context()
+	s, ok := getAnalysis(c)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	signals := ui_store.NewAnalysisSignals()
+	datastar.ReadSignals(c.Request, signals)
+
+	if err := s.SetFEN(signals.FEN); err != nil {
+		logger.Info(ctx).Err(err).Msg("Invalid FEN")
+		signals.FENError = err.Error()
+		if err := broadcastAnalysisSignals(c, signals); err != nil {
+			logger.Error(ctx).Err(err).Msg("Failed to broadcast FEN error")
+		}
+		return
+	}
+
+	if err := broadcastAnalysis(c, s.Snapshot()); err != nil {
+		logger.Error(ctx).Err(err).Msg("Failed to broadcast analysis board")
+	}
+}
+
+func AnalysisBack(c *gin.Context) {
+	seekAnalysis(c, func(cursor, _ int) int { return cursor - 1 })
+}
+
+func AnalysisForward(c *gin.Context) {
+	seekAnalysis(c, func(cursor, _ int) int { return cursor + 1 })
+}
+
+func AnalysisEnd(c *gin.Context) {
+	seekAnalysis(c, func(_, n int) int { return n })
+}
+
+// AnalysisSeek jumps to the position after :ply moves
+func AnalysisSeek(c *gin.Context) {
+	ply, err := strconv.Atoi(c.Param("ply"))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	seekAnalysis(c, func(_, _ int) int { return ply })
+}
+
+func seekAnalysis(c *gin.Context, where func(cursor, n int) int) {
+	ctx := c.Request.Context()
+	s, ok := getAnalysis(c)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if err := s.Seek(where); err != nil {
+		logger.Info(ctx).Err(err).Msg("Analysis seek")
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	if err := broadcastAnalysis(c, s.Snapshot()); err != nil {
+		logger.Error(ctx).Err(err).Msg("Failed to broadcast analysis board")
+	}
+}
+
+// AnalysisEvents streams the engine's evaluation and best line as it
+// deepens, and the board whenever another tab changes the position
+func AnalysisEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+	s, ok := getAnalysis(c)
+	logger.Info(ctx).Bool("analysis found", ok).Msg("Handler: AnalysisEvents")
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warn(ctx).Err(err).Msg("Could not clear write deadline")
+	}
+
+	events, cancel := s.Subscribe()
+	defer cancel()
+
+	sse := datastar.NewSSE(c.Writer, c.Request)
+
+	// The first iterations may have finished before the stream connected
+	if err := patchEval(ctx, sse, s.Snapshot().Eval); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-sse.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			snap := s.Snapshot()
+			var err error
+			if e.Type == analysis.EventPosition {
+				err = patchAnalysis(ctx, sse, snap)
+			} else {
+				err = patchEval(ctx, sse, snap.Eval)
+			}
+			if err != nil {
+				logger.Error(ctx).Err(err).Msg("Failed to stream analysis event")
+				return
+			}
+		}
+	}
+}
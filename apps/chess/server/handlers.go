@@ -1,13 +1,17 @@
 package server
 
 import (
+	"errors"
+	"math/rand/v2"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lordsonvimal/synergy/apps/chess/engine"
 	"github.com/lordsonvimal/synergy/apps/chess/game"
 	"github.com/lordsonvimal/synergy/apps/chess/logger"
+	"github.com/lordsonvimal/synergy/apps/chess/pgn"
 	"github.com/lordsonvimal/synergy/apps/chess/store"
 	"github.com/lordsonvimal/synergy/apps/chess/ui/pages"
 	"github.com/lordsonvimal/synergy/apps/chess/ui/ui_store"
@@ -16,7 +20,8 @@ import (
 
 func ShowGameModes(c *gin.Context) {
 	modes := game.ListGameModes()
-	Render(c, http.StatusOK, pages.GameModesPage(modes))
+	levels := game.ListBotLevels()
+	Render(c, http.StatusOK, pages.GameModesPage(modes, levels))
 }
 
 func CreateGame(c *gin.Context) {
@@ -26,6 +31,8 @@ func CreateGame(c *gin.Context) {
 		return
 	}
 
+	playerID, _ := store.GetPlayerIDFromContext(c.Request.Context())
+
 	selectedMode := c.PostForm("mode")
 	gm, err := game.FindGameModeByName(selectedMode)
 	if err != nil {
@@ -33,14 +40,105 @@ func CreateGame(c *gin.Context) {
 		return
 	}
 
-	g := game.NewGame(&gm)
+	var g *game.Game
+	if c.PostForm("opponent") == "computer" {
+		g, err = newBotGame(c, &gm, playerID)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	} else {
+		g = game.NewGame(&gm, playerID)
+	}
 	repo.Add(g)
 
+	if reviews, ok := store.GetReviewRepoFromContext(c.Request.Context()); ok {
+		go reviewOnEnd(g, reviews)
+	}
+
 	// For simplicity, you can store in-memory or use session/DB
 	// For now, render the chessboard page
 	Render(c, http.StatusOK, pages.NewGamePage(g))
 }
 
+// gamesPageSize is how many games the history page lists at a time
+const gamesPageSize = 20
+
+// ListGames shows a page of the player's past games
+func ListGames(c *gin.Context) {
+	ctx := c.Request.Context()
+	repo, ok := store.GetRepoFromContext(ctx)
+	logger.Info(ctx).Bool("repo found", ok).Msg("Handler: ListGames")
+	if !ok {
+		return
+	}
+	playerID, ok := store.GetPlayerIDFromContext(ctx)
+	if !ok {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	// One extra game tells whether there is a next page
+	games, err := repo.ListByPlayer(playerID, (page-1)*gamesPageSize, gamesPageSize+1)
+	if err != nil {
+		logger.Error(ctx).Err(err).Msg("Failed to list games")
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	hasNext := len(games) > gamesPageSize
+	if hasNext {
+		games = games[:gamesPageSize]
+	}
+
+	Render(c, http.StatusOK, pages.GamesPage(games, page, hasNext))
+}
+
+// RestoreGames registers games recovered from their WALs and, as
+// CreateGame does, reviews each ongoing one once it ends. An engine whose
+// turn it was moves right away: its clock is already running.
+func RestoreGames(games []*game.Game, repo store.GameRepository, reviews store.ReviewRepository) {
+	for _, g := range games {
+		repo.Add(g)
+		if state, _ := g.Outcome(); state == game.GameOngoing {
+			go reviewOnEnd(g, reviews)
+		}
+		if g.IsBotTurn() {
+			go g.PlayBotMove()
+		}
+	}
+}
+
+// newBotGame reads the engine's level and the human's color from the form
+func newBotGame(c *gin.Context, gm *game.GameMode, playerID string) (*game.Game, error) {
+	levelNum, err := strconv.Atoi(c.PostForm("level"))
+	if err != nil {
+		return nil, errors.New("invalid bot level")
+	}
+	level, err := game.FindBotLevel(levelNum)
+	if err != nil {
+		return nil, errors.New("invalid bot level")
+	}
+
+	var botColor engine.Color
+	switch c.PostForm("color") {
+	case "white":
+		botColor = engine.Black
+	case "black":
+		botColor = engine.White
+	case "random", "":
+		botColor = engine.Color(rand.IntN(2))
+	default:
+		return nil, errors.New("invalid color")
+	}
+
+	return game.NewBotGame(gm, level, botColor, playerID), nil
+}
+
 func SelectSquare(c *gin.Context) {
 	ctx := c.Request.Context()
 	repo, ok := store.GetRepoFromContext(ctx)
@@ -75,6 +173,12 @@ func SelectSquare(c *gin.Context) {
 		return
 	}
 
+	// The engine's pieces are not selectable, and it may still be thinking
+	if g.IsBotTurn() {
+		logger.Info(ctx).Msg("Engine to move")
+		return
+	}
+
 	signals := ui_store.NewChessBoardSignals()
 	datastar.ReadSignals(c.Request, signals)
 
@@ -107,6 +211,16 @@ func SelectSquare(c *gin.Context) {
 			err := broadcastBoard(c, g, signals)
 			if err != nil {
 				logger.Error(ctx).Err(err).Msg("Failed to broadcast board update")
+				return
+			}
+
+			// vs Computer: reply on the same stream once the engine has moved
+			if g.PlayBotMove() {
+				signals.UpdateFromGame(g)
+				err = broadcastBoard(c, g, signals)
+				if err != nil {
+					logger.Error(ctx).Err(err).Msg("Failed to broadcast engine move")
+				}
 			}
 			return
 
@@ -123,3 +237,123 @@ func SelectSquare(c *gin.Context) {
 		logger.Error(ctx).Err(err).Msg("Failed to broadcast selection update")
 	}
 }
+
+func ClaimDraw(c *gin.Context) {
+	ctx := c.Request.Context()
+	repo, ok := store.GetRepoFromContext(ctx)
+	logger.Info(ctx).Bool("repo found", ok).Msg("Handler: ClaimDraw")
+	if !ok {
+		return
+	}
+
+	gameID, ok := c.Params.Get("gameID")
+	if !ok {
+		logger.Error(ctx).Str("gameID found", gameID).Msg("GameID")
+		return
+	}
+
+	g, ok := repo.Get(gameID)
+	if !ok {
+		return
+	}
+
+	if !g.ClaimDraw() {
+		logger.Info(ctx).Msg("Invalid draw claim")
+	}
+
+	signals := ui_store.NewChessBoardSignals()
+	datastar.ReadSignals(c.Request, signals)
+	signals.UpdateFromGame(g)
+
+	err := broadcastSignals(c, signals)
+	if err != nil {
+		logger.Error(ctx).Err(err).Msg("Failed to broadcast draw claim")
+	}
+}
+
+// GameEvents keeps an SSE stream open and pushes the board whenever the
+// game changes outside of this client's own requests (clock flags, the
+// opponent's moves, ...)
+func GameEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+	repo, ok := store.GetRepoFromContext(ctx)
+	logger.Info(ctx).Bool("repo found", ok).Msg("Handler: GameEvents")
+	if !ok {
+		return
+	}
+
+	gameID, ok := c.Params.Get("gameID")
+	if !ok {
+		logger.Error(ctx).Str("gameID found", gameID).Msg("GameID")
+		return
+	}
+
+	g, ok := repo.Get(gameID)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warn(ctx).Err(err).Msg("Could not clear write deadline")
+	}
+
+	events, cancel := g.Subscribe()
+	defer cancel()
+
+	// The engine opens when it plays white; its move arrives as an event
+	if g.IsBotTurn() {
+		go g.PlayBotMove()
+	}
+
+	sse := datastar.NewSSE(c.Writer, c.Request)
+	for {
+		select {
+		case <-sse.Context().Done():
+			return
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			signals := ui_store.NewChessBoardSignals()
+			signals.UpdateFromGame(g)
+			if err := patchBoard(ctx, sse, g, signals); err != nil {
+				logger.Error(ctx).Err(err).Msg("Failed to stream game event")
+				return
+			}
+		}
+	}
+}
+
+// ExportPGN downloads the game in PGN format
+func ExportPGN(c *gin.Context) {
+	ctx := c.Request.Context()
+	repo, ok := store.GetRepoFromContext(ctx)
+	logger.Info(ctx).Bool("repo found", ok).Msg("Handler: ExportPGN")
+	if !ok {
+		return
+	}
+
+	gameID, ok := c.Params.Get("gameID")
+	if !ok {
+		logger.Error(ctx).Str("gameID found", gameID).Msg("GameID")
+		return
+	}
+
+	g, ok := repo.Get(gameID)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	text, err := pgn.Export(g)
+	if err != nil {
+		logger.Error(ctx).Err(err).Msg("Failed to export PGN")
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="game_`+g.ID+`.pgn"`)
+	c.Data(http.StatusOK, "application/x-chess-pgn", []byte(text))
+}
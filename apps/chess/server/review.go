@@ -0,0 +1,120 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lordsonvimal/synergy/apps/chess/game"
+	"github.com/lordsonvimal/synergy/apps/chess/logger"
+	"github.com/lordsonvimal/synergy/apps/chess/review"
+	"github.com/lordsonvimal/synergy/apps/chess/store"
+	"github.com/lordsonvimal/synergy/apps/chess/ui/components"
+	"github.com/lordsonvimal/synergy/apps/chess/ui/pages"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// reviewPollInterval is how often the review page's progress is refreshed
+const reviewPollInterval = 500 * time.Millisecond
+
+// reviewOnEnd starts the game's review as soon as it ends, so the report
+// is usually ready by the time a player opens it. Abandoned games are only
+// reviewed on request.
+func reviewOnEnd(g *game.Game, reviews store.ReviewRepository) {
+	events, cancel := g.Subscribe()
+	defer cancel()
+
+	for e := range events {
+		if e.State != game.GameOngoing {
+			if e.State != game.GameAbandoned {
+				reviews.GetOrStart(g)
+			}
+			return
+		}
+	}
+}
+
+// ShowReview renders a finished game's review, or its progress while the
+// job is still running
+func ShowReview(c *gin.Context) {
+	g, reviews, ok := getReviewGame(c)
+	if !ok {
+		return
+	}
+
+	job := reviews.GetOrStart(g)
+	Render(c, http.StatusOK, pages.ReviewPage(g.ID, reviewView(job)))
+}
+
+// ReviewEvents streams the job's progress until the report is ready
+func ReviewEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+	g, reviews, ok := getReviewGame(c)
+	if !ok {
+		return
+	}
+	job := reviews.GetOrStart(g)
+
+	// The stream outlives the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warn(ctx).Err(err).Msg("Could not clear write deadline")
+	}
+
+	sse := datastar.NewSSE(c.Writer, c.Request)
+	ticker := time.NewTicker(reviewPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sse.Context().Done():
+			return
+		case <-job.Finished():
+			if err := patchReview(ctx, sse, g.ID, reviewView(job)); err != nil {
+				logger.Error(ctx).Err(err).Msg("Failed to stream review")
+			}
+			return
+		case <-ticker.C:
+			if err := patchReview(ctx, sse, g.ID, reviewView(job)); err != nil {
+				logger.Error(ctx).Err(err).Msg("Failed to stream review progress")
+				return
+			}
+		}
+	}
+}
+
+// getReviewGame looks up the :gameID game, answering the request itself
+// when it is missing or still being played
+func getReviewGame(c *gin.Context) (*game.Game, store.ReviewRepository, bool) {
+	ctx := c.Request.Context()
+	repo, ok := store.GetRepoFromContext(ctx)
+	reviews, rok := store.GetReviewRepoFromContext(ctx)
+	logger.Info(ctx).Bool("repo found", ok).Bool("review repo found", rok).Msg("Handler: Review")
+	if !ok || !rok {
+		c.Status(http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	g, ok := repo.Get(c.Param("gameID"))
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	if state, _ := g.Outcome(); state == game.GameOngoing {
+		c.String(http.StatusConflict, "The game is still in progress")
+		return nil, nil, false
+	}
+	return g, reviews, true
+}
+
+func reviewView(job *review.Job) components.ReviewView {
+	done, total := job.Progress()
+	v := components.ReviewView{Done: done, Total: total}
+	select {
+	case <-job.Finished():
+		v.Report, v.Err = job.Result()
+		v.Finished = true
+	default:
+	}
+	return v
+}
@@ -5,5 +5,22 @@ import "github.com/gin-gonic/gin"
 func InitRoutes(r *gin.Engine) {
 	r.GET("/", ShowGameModes)
 	r.POST("/game", CreateGame)
+	r.GET("/games", ListGames)
 	r.POST("/game/:gameID/select/:square", SelectSquare)
+	r.POST("/game/:gameID/claim-draw", ClaimDraw)
+	r.GET("/game/:gameID/events", GameEvents)
+	r.GET("/game/:gameID/ws", GameWS)
+	r.GET("/game/:gameID/pgn", ExportPGN)
+	r.GET("/game/:gameID/review", ShowReview)
+	r.GET("/game/:gameID/review/events", ReviewEvents)
+
+	r.POST("/analysis", NewAnalysis)
+	r.GET("/analysis/:analysisID", ShowAnalysis)
+	r.POST("/analysis/:analysisID/select/:square", AnalysisSelectSquare)
+	r.POST("/analysis/:analysisID/fen", AnalysisLoadFEN)
+	r.POST("/analysis/:analysisID/back", AnalysisBack)
+	r.POST("/analysis/:analysisID/forward", AnalysisForward)
+	r.POST("/analysis/:analysisID/end", AnalysisEnd)
+	r.POST("/analysis/:analysisID/seek/:ply", AnalysisSeek)
+	r.GET("/analysis/:analysisID/events", AnalysisEvents)
 }
@@ -1,10 +1,12 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lordsonvimal/synergy/apps/chess/analysis"
 	"github.com/lordsonvimal/synergy/apps/chess/game"
 	"github.com/lordsonvimal/synergy/apps/chess/ui/components"
 	"github.com/lordsonvimal/synergy/apps/chess/ui/ui_store"
@@ -14,24 +16,94 @@ import (
 // Broadcast the updated board state to the client
 func broadcastBoard(c *gin.Context, g *game.Game, signals *ui_store.ChessBoardSignals) error {
 	sse := datastar.NewSSE(c.Writer, c.Request)
+	return patchBoard(c.Request.Context(), sse, g, signals)
+}
+
+// Show promotion UI for selecting a piece
+func broadcastSignals(c *gin.Context, signals *ui_store.ChessBoardSignals) error {
+	sse := datastar.NewSSE(c.Writer, c.Request)
+	return patchSignals(sse, signals)
+}
 
+func patchBoard(ctx context.Context, sse *datastar.ServerSentEventGenerator, g *game.Game, signals *ui_store.ChessBoardSignals) error {
 	buf := new(strings.Builder)
-	components.RenderChessBoard(g).Render(c.Request.Context(), buf)
+	components.RenderChessBoard(g).Render(ctx, buf)
 
-	sse.PatchElements(buf.String())
+	if err := sse.PatchElements(buf.String()); err != nil {
+		return err
+	}
+
+	buf.Reset()
+	components.RenderMoveList(g).Render(ctx, buf)
+	if err := sse.PatchElements(buf.String()); err != nil {
+		return err
+	}
 
-	return broadcastSignals(c, signals)
+	return patchSignals(sse, signals)
 }
 
-// Show promotion UI for selecting a piece
-func broadcastSignals(c *gin.Context, signals *ui_store.ChessBoardSignals) error {
-	sse := datastar.NewSSE(c.Writer, c.Request)
+func patchSignals(sse *datastar.ServerSentEventGenerator, signals *ui_store.ChessBoardSignals) error {
+	return patchJSONSignals(sse, signals)
+}
 
+func patchJSONSignals(sse *datastar.ServerSentEventGenerator, signals any) error {
 	b, err := json.Marshal(signals)
 	if err != nil {
 		return err
 	}
 
-	sse.PatchSignals(b)
-	return nil
+	return sse.PatchSignals(b)
+}
+
+// Broadcast the updated analysis board to the client
+func broadcastAnalysis(c *gin.Context, snap analysis.Snapshot) error {
+	sse := datastar.NewSSE(c.Writer, c.Request)
+	return patchAnalysis(c.Request.Context(), sse, snap)
+}
+
+func broadcastAnalysisSignals(c *gin.Context, signals *ui_store.AnalysisSignals) error {
+	sse := datastar.NewSSE(c.Writer, c.Request)
+	return patchJSONSignals(sse, signals)
+}
+
+func patchAnalysis(ctx context.Context, sse *datastar.ServerSentEventGenerator, snap analysis.Snapshot) error {
+	buf := new(strings.Builder)
+	components.RenderAnalysisBoard(snap).Render(ctx, buf)
+	if err := sse.PatchElements(buf.String()); err != nil {
+		return err
+	}
+
+	buf.Reset()
+	components.RenderAnalysisMoves(snap).Render(ctx, buf)
+	if err := sse.PatchElements(buf.String()); err != nil {
+		return err
+	}
+
+	if err := patchEval(ctx, sse, snap.Eval); err != nil {
+		return err
+	}
+
+	signals := ui_store.NewAnalysisSignals()
+	signals.UpdateFromSnapshot(snap)
+	signals.PromoteTo = "" // keep the user's choice
+	return patchJSONSignals(sse, signals)
+}
+
+// patchEval updates the eval bar and the engine's best line
+func patchEval(ctx context.Context, sse *datastar.ServerSentEventGenerator, eval analysis.Eval) error {
+	buf := new(strings.Builder)
+	components.RenderEvalBar(eval).Render(ctx, buf)
+	if err := sse.PatchElements(buf.String()); err != nil {
+		return err
+	}
+
+	buf.Reset()
+	components.RenderBestLine(eval).Render(ctx, buf)
+	return sse.PatchElements(buf.String())
+}
+
+func patchReview(ctx context.Context, sse *datastar.ServerSentEventGenerator, gameID string, v components.ReviewView) error {
+	buf := new(strings.Builder)
+	components.RenderReview(gameID, v).Render(ctx, buf)
+	return sse.PatchElements(buf.String())
 }
@@ -2,11 +2,16 @@ package server
 
 import (
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
-	"github.com/lordsonvimal/synergy/apps/chess/engine"
 	"github.com/lordsonvimal/synergy/apps/chess/game"
+	"github.com/lordsonvimal/synergy/apps/chess/logger"
+	"github.com/lordsonvimal/synergy/apps/chess/store"
 )
 
 // --------------------------
@@ -17,123 +22,316 @@ type MoveMsg struct {
 	RTT int64  `json:"rtt"` // round-trip time in nanoseconds
 }
 
+const (
+	wsWriteWait    = 10 * time.Second // for one message to be written
+	wsPongWait     = 60 * time.Second // for the peer's pong before giving up on it
+	wsPingPeriod   = wsPongWait * 9 / 10
+	wsMaxMessage   = 512         // bytes; a move message is far smaller
+	wsSendBuffer   = 16          // messages queued per socket before it is dropped
+	wsMaxLagCompNs = 150_000_000 // lag compensation is capped at 150ms
+)
+
 // --------------------------
 // WebSocket upgrader
 // --------------------------
+
+// AllowedOrigins lists the origins, such as "https://chess.example.com",
+// whose pages may open game sockets besides the server's own
+var AllowedOrigins []string
+
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+	CheckOrigin: checkOrigin,
+}
+
+// checkOrigin accepts sockets opened by pages served from this host or an
+// allowed origin. Browsers always send an Origin; other clients, which may
+// not, are let through.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range AllowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// --------------------------
+// Socket
+// --------------------------
+
+// wsClient is one open socket. Only its write goroutine writes to conn;
+// everyone else queues messages on send.
+type wsClient struct {
+	conn *websocket.Conn
+	send chan any
+}
+
+// writePump writes queued messages and keeps the connection alive with
+// pings. It closes the connection once send is closed or a write fails.
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
 }
 
 // --------------------------
 // Active connections per game
 // --------------------------
+
+// GameConnections is a game's hub: its open sockets, and a goroutine
+// pushing a snapshot to all of them on each game event, whatever caused it
+// (a move over any transport, the engine, a flag, ...)
 type GameConnections struct {
+	game   *game.Game
+	cancel func() // ends the event subscription
+
 	mu    sync.Mutex
-	conns map[*websocket.Conn]struct{}
+	conns map[*wsClient]struct{}
+}
+
+func NewGameConnections(g *game.Game) *GameConnections {
+	events, cancel := g.Subscribe()
+	gc := &GameConnections{
+		game:   g,
+		cancel: cancel,
+		conns:  make(map[*wsClient]struct{}),
+	}
+	go gc.run(events)
+	return gc
 }
 
-func NewGameConnections() *GameConnections {
-	return &GameConnections{
-		conns: make(map[*websocket.Conn]struct{}),
+// run broadcasts a snapshot per event. Snapshots are complete, so events
+// dropped while the hub lags behind lose nothing.
+func (gc *GameConnections) run(events <-chan game.Event) {
+	for range events {
+		gc.Broadcast(gameSnapshot(gc.game.Snapshot(), "", "ok"))
 	}
 }
 
-func (gc *GameConnections) Add(conn *websocket.Conn) {
+// Close stops the hub's broadcasts
+func (gc *GameConnections) Close() {
+	gc.cancel()
+}
+
+func (gc *GameConnections) Add(c *wsClient) {
 	gc.mu.Lock()
 	defer gc.mu.Unlock()
-	gc.conns[conn] = struct{}{}
+	gc.conns[c] = struct{}{}
 }
 
-func (gc *GameConnections) Remove(conn *websocket.Conn) {
+// Remove unregisters the client and closes its queue, returning how many
+// clients are left
+func (gc *GameConnections) Remove(c *wsClient) int {
 	gc.mu.Lock()
 	defer gc.mu.Unlock()
-	delete(gc.conns, conn)
+	gc.drop(c)
+	return len(gc.conns)
 }
 
+// Broadcast queues msg for every client. A client whose queue is full is
+// dropped rather than waited on.
 func (gc *GameConnections) Broadcast(msg any) {
 	gc.mu.Lock()
 	defer gc.mu.Unlock()
-	for conn := range gc.conns {
-		conn.WriteJSON(msg)
+	for c := range gc.conns {
+		gc.enqueue(c, msg)
 	}
 }
 
-// Convert MoveStack to a slice of UCI strings
-func movesFromStack(b *engine.Board) []string {
-	moves := make([]string, len(b.MoveStack))
-	for i, ms := range b.MoveStack {
-		move := engine.Move{
-			From:      ms.From,
-			To:        ms.To,
-			Promotion: ms.Promotion,
-			Flags:     ms.Flags,
-		}
-		moves[i] = move.ToUCI() // assumes you have Move.ToUCI() implemented
+// Send queues msg for a single client
+func (gc *GameConnections) Send(c *wsClient, msg any) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if _, ok := gc.conns[c]; ok {
+		gc.enqueue(c, msg)
+	}
+}
+
+// enqueue and drop must be called with gc.mu held
+func (gc *GameConnections) enqueue(c *wsClient, msg any) {
+	select {
+	case c.send <- msg:
+	default:
+		gc.drop(c)
+	}
+}
+
+func (gc *GameConnections) drop(c *wsClient) {
+	if _, ok := gc.conns[c]; ok {
+		delete(gc.conns, c)
+		close(c.send)
+	}
+}
+
+// --------------------------
+// Hubs by game
+// --------------------------
+
+// hubRegistry maps game IDs to their hubs. A hub is created with a game's
+// first socket and closed with its last.
+type hubRegistry struct {
+	mu   sync.Mutex
+	hubs map[string]*GameConnections
+}
+
+var gameHubs = &hubRegistry{hubs: make(map[string]*GameConnections)}
+
+func (r *hubRegistry) join(g *game.Game, c *wsClient) *GameConnections {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	gc, ok := r.hubs[g.ID]
+	if !ok {
+		gc = NewGameConnections(g)
+		r.hubs[g.ID] = gc
+	}
+	gc.Add(c)
+	return gc
+}
+
+func (r *hubRegistry) leave(gameID string, c *wsClient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	gc, ok := r.hubs[gameID]
+	if !ok {
+		return
+	}
+	if gc.Remove(c) == 0 {
+		gc.Close()
+		delete(r.hubs, gameID)
 	}
-	return moves
 }
 
 // --------------------------
 // Helper: create snapshot
 // --------------------------
-func gameSnapshot(g *game.Game, lastMove string, status string) map[string]any {
+
+// gameSnapshot is the message sent to clients. lastMove defaults to the
+// last move played.
+func gameSnapshot(snap game.GameSnapshot, lastMove string, status string) map[string]any {
+	if lastMove == "" && len(snap.UCIMoves) > 0 {
+		lastMove = snap.UCIMoves[len(snap.UCIMoves)-1]
+	}
 	return map[string]any{
-		"seq":      g.Seq,
-		"board":    g.Board.FEN(),
+		"seq":      snap.Seq,
+		"board":    snap.FEN,
 		"lastMove": lastMove, // for UI animation
 		"status":   status,   // "ok" or "illegal"
 		"clock": map[string]int64{
-			"white": g.Clock.White.RemainingNs,
-			"black": g.Clock.Black.RemainingNs,
+			"white": snap.WhiteNs,
+			"black": snap.BlackNs,
 		},
-		"check":     g.IsCheck(),
-		"checkmate": g.IsCheckmate(),
-		"stalemate": g.IsStalemate(),
-		"moves":     movesFromStack(g.Board), // optional PGN / move list
+		"check":     snap.Check,
+		"checkmate": snap.State == game.GameCheckmate,
+		"stalemate": snap.State == game.GameDrawStalemate,
+		"moves":     snap.UCIMoves, // optional PGN / move list
+		"san":       snap.SANMoves,
 	}
 }
 
 // --------------------------
 // Game WebSocket handler
 // --------------------------
-func GameWS(g *game.Game, gc *GameConnections) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		conn, err := upgrader.Upgrade(w, r, nil)
-		if err != nil {
-			http.Error(w, "Could not open websocket", http.StatusBadRequest)
-			return
-		}
-		defer conn.Close()
-		gc.Add(conn)
-		defer gc.Remove(conn)
 
-		// Send initial snapshot
-		conn.WriteJSON(gameSnapshot(g, "", "ok"))
+// GameWS plays a game over a WebSocket: the client sends moves in UCI and
+// receives a snapshot after every change to the game
+func GameWS(c *gin.Context) {
+	ctx := c.Request.Context()
+	repo, ok := store.GetRepoFromContext(ctx)
+	logger.Info(ctx).Bool("repo found", ok).Msg("Handler: GameWS")
+	if !ok {
+		return
+	}
 
-		for {
-			var msg MoveMsg
-			if err := conn.ReadJSON(&msg); err != nil {
-				return // client disconnected
-			}
+	gameID, ok := c.Params.Get("gameID")
+	if !ok {
+		logger.Error(ctx).Str("gameID found", gameID).Msg("GameID")
+		return
+	}
 
-			// Compute lag-compensated time (cap 150ms)
-			lagNs := msg.RTT / 2
-			if lagNs > 150_000_000 {
-				lagNs = 150_000_000
-			}
+	g, ok := repo.Get(gameID)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
 
-			// Parse UCI move
-			move := engine.MoveFromUCI(msg.UCI)
-
-			// Apply move
-			if g.ApplyMove(move, lagNs) {
-				// Legal move
-				snapshot := gameSnapshot(g, msg.UCI, "ok")
-				gc.Broadcast(snapshot)
-			} else {
-				// Illegal move, notify only this client
-				conn.WriteJSON(gameSnapshot(g, msg.UCI, "illegal"))
-			}
+	// The upgrader answers failed handshakes itself
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logger.Warn(ctx).Err(err).Msg("WebSocket upgrade failed")
+		return
+	}
+
+	client := &wsClient{conn: conn, send: make(chan any, wsSendBuffer)}
+	hub := gameHubs.join(g, client)
+	defer gameHubs.leave(g.ID, client)
+	go client.writePump()
+
+	// Send initial snapshot
+	hub.Send(client, gameSnapshot(g.Snapshot(), "", "ok"))
+
+	// The engine opens when it plays white; its move arrives as an event
+	if g.IsBotTurn() {
+		go g.PlayBotMove()
+	}
+
+	conn.SetReadLimit(wsMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var msg MoveMsg
+		if err := conn.ReadJSON(&msg); err != nil {
+			return // client disconnected
+		}
+
+		// Compute lag-compensated time
+		lagNs := min(max(msg.RTT/2, 0), wsMaxLagCompNs)
+
+		// The engine's moves are its own to make
+		move, err := g.ParseUCIMove(msg.UCI)
+		if err != nil || g.IsBotTurn() || !g.ApplyMove(move, lagNs) {
+			// Illegal move, notify only this client
+			hub.Send(client, gameSnapshot(g.Snapshot(), msg.UCI, "illegal"))
+			continue
+		}
+
+		// The move reaches every socket, this one included, as a game event
+		if g.IsBotTurn() {
+			go g.PlayBotMove()
 		}
 	}
 }
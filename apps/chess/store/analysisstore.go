@@ -0,0 +1,70 @@
+package store
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/lordsonvimal/synergy/apps/chess/analysis"
+)
+
+// ErrTooManyAnalyses is returned by Add when every analysis slot is taken
+var ErrTooManyAnalyses = errors.New("too many analysis boards open")
+
+type AnalysisRepository interface {
+	Add(*analysis.Session) error
+	Get(id string) (*analysis.Session, bool)
+	Delete(id string)
+	Loaded() []*analysis.Session
+}
+
+type AnalysisStore struct {
+	mu          sync.RWMutex
+	sessions    map[string]*analysis.Session
+	maxSessions int
+}
+
+// NewAnalysisStore holds up to maxSessions sessions at once, or any number
+// if maxSessions is 0
+func NewAnalysisStore(maxSessions int) *AnalysisStore {
+	return &AnalysisStore{
+		sessions:    make(map[string]*analysis.Session),
+		maxSessions: maxSessions,
+	}
+}
+
+func (s *AnalysisStore) Add(a *analysis.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxSessions > 0 && len(s.sessions) >= s.maxSessions {
+		return ErrTooManyAnalyses
+	}
+	s.sessions[a.ID] = a
+	return nil
+}
+
+func (s *AnalysisStore) Get(id string) (*analysis.Session, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.sessions[id]
+	return a, ok
+}
+
+// Delete removes the session and stops its search
+func (s *AnalysisStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.sessions[id]; ok {
+		a.Close()
+		delete(s.sessions, id)
+	}
+}
+
+func (s *AnalysisStore) Loaded() []*analysis.Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sessions := make([]*analysis.Session, 0, len(s.sessions))
+	for _, a := range s.sessions {
+		sessions = append(sessions, a)
+	}
+	return sessions
+}
@@ -1,8 +1,11 @@
 package store
 
 import (
+	"slices"
 	"sync"
+	"time"
 
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
 	"github.com/lordsonvimal/synergy/apps/chess/game"
 )
 
@@ -10,12 +13,56 @@ type GameRepository interface {
 	Add(*game.Game)
 	Get(id string) (*game.Game, bool)
 	Delete(id string)
+	// Loaded returns the games held in memory
+	Loaded() []*game.Game
+	// Evict drops the game from memory. Stores that persist games keep it.
+	Evict(id string)
+	// ListByPlayer returns a page of the games the player created, newest
+	// first
+	ListByPlayer(playerID string, offset, limit int) ([]GameSummary, error)
 }
 
 type ChessGlobalContext struct {
 	Games GameRepository
 }
 
+// GameSummary is one game in a player's history
+type GameSummary struct {
+	ID        string
+	Mode      string
+	StartedAt time.Time
+	State     game.GameState
+	Winner    engine.Color
+	BotColor  engine.Color // NoColor in human vs human games
+	BotLevel  int
+	Moves     int // half-moves played
+}
+
+// summarize reads a game's summary from its WAL and outcome
+func summarize(g *game.Game) GameSummary {
+	header := g.WAL.Header()
+	state, winner := g.Outcome()
+	s := GameSummary{
+		ID:        g.ID,
+		Mode:      header.Mode,
+		StartedAt: g.StartedAt,
+		State:     state,
+		Winner:    winner,
+		BotColor:  engine.NoColor,
+	}
+	for c := engine.Color(0); c < engine.ColorNB; c++ {
+		if level := header.Player(c).BotLevel; level != 0 {
+			s.BotColor, s.BotLevel = c, level
+		}
+	}
+	for _, e := range g.WAL.LoadFromMemory() {
+		if e.Type == game.WALEventMove || e.Type == "" {
+			s.Moves++
+		}
+	}
+	return s
+}
+
 type GameStore struct {
 	mu    sync.RWMutex
 	games map[string]*game.Game
@@ -45,3 +92,41 @@ func (s *GameStore) Delete(id string) {
 	defer s.mu.Unlock()
 	delete(s.games, id)
 }
+
+func (s *GameStore) Loaded() []*game.Game {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	games := make([]*game.Game, 0, len(s.games))
+	for _, g := range s.games {
+		games = append(games, g)
+	}
+	return games
+}
+
+// Evict deletes the game, as nothing else holds it
+func (s *GameStore) Evict(id string) {
+	s.Delete(id)
+}
+
+func (s *GameStore) ListByPlayer(playerID string, offset, limit int) ([]GameSummary, error) {
+	s.mu.RLock()
+	var games []*game.Game
+	for _, g := range s.games {
+		if g.PlayerID == playerID {
+			games = append(games, g)
+		}
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(games, func(a, b *game.Game) int {
+		return b.StartedAt.Compare(a.StartedAt)
+	})
+	games = games[min(max(offset, 0), len(games)):]
+	games = games[:min(max(limit, 0), len(games))]
+
+	summaries := make([]GameSummary, len(games))
+	for i, g := range games {
+		summaries[i] = summarize(g)
+	}
+	return summaries, nil
+}
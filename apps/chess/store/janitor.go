@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"github.com/lordsonvimal/synergy/apps/chess/game"
+	"github.com/lordsonvimal/synergy/apps/chess/logger"
+)
+
+// Totals since startup, served with the rest of expvar at /debug/vars
+var (
+	gamesAbandoned = expvar.NewInt("games_abandoned")
+	gamesEvicted   = expvar.NewInt("games_evicted")
+	analysesClosed = expvar.NewInt("analyses_closed")
+)
+
+// --------------------------
+// Janitor
+// --------------------------
+
+// Janitor frees games nobody is using. An ongoing game idle past
+// IdleTimeout is abandoned; a finished game idle past FinishedTTL, or one
+// just abandoned, has its WAL archived and is evicted from memory along
+// with its review. An analysis board idle past AnalysisTimeout is closed.
+type Janitor struct {
+	Games           GameRepository
+	Reviews         ReviewRepository   // may be nil
+	Analyses        AnalysisRepository // may be nil
+	IdleTimeout     time.Duration
+	FinishedTTL     time.Duration
+	AnalysisTimeout time.Duration
+	Interval        time.Duration // between sweeps
+}
+
+// Run sweeps every Interval until ctx is done
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			abandoned, evicted := j.Sweep(now)
+			if abandoned > 0 || evicted > 0 {
+				counts := CountGames(j.Games)
+				logger.Info(ctx).
+					Int("abandoned", abandoned).
+					Int("evicted", evicted).
+					Int("ongoing", counts.Ongoing).
+					Int("finished", counts.Finished).
+					Msg("Janitor swept idle games")
+			}
+			if closed := j.SweepAnalyses(now); closed > 0 {
+				logger.Info(ctx).Int("closed", closed).Msg("Janitor closed idle analysis boards")
+			}
+		}
+	}
+}
+
+// Sweep abandons and evicts the games idle as of now, returning how many
+func (j *Janitor) Sweep(now time.Time) (abandoned, evicted int) {
+	for _, g := range j.Games.Loaded() {
+		idle := now.Sub(g.LastActive())
+		if state, _ := g.Outcome(); state == game.GameOngoing {
+			// A game ending meanwhile is left for the next sweep
+			if idle < j.IdleTimeout || !g.Abandon() {
+				continue
+			}
+			abandoned++
+			gamesAbandoned.Add(1)
+		} else if idle < j.FinishedTTL {
+			continue
+		}
+
+		// Evicted even if archiving fails; the WAL is then recovered on restart
+		if err := g.WAL.Archive(); err != nil {
+			logger.Warn(context.Background()).Err(err).Str("game", g.ID).Msg("Failed to archive WAL")
+		}
+		j.Games.Evict(g.ID)
+		if j.Reviews != nil {
+			j.Reviews.Delete(g.ID)
+		}
+		evicted++
+		gamesEvicted.Add(1)
+	}
+	return abandoned, evicted
+}
+
+// SweepAnalyses closes the analysis boards idle as of now, returning how
+// many
+func (j *Janitor) SweepAnalyses(now time.Time) (closed int) {
+	if j.Analyses == nil {
+		return 0
+	}
+	for _, a := range j.Analyses.Loaded() {
+		if now.Sub(a.LastActive()) < j.AnalysisTimeout {
+			continue
+		}
+		j.Analyses.Delete(a.ID)
+		closed++
+		analysesClosed.Add(1)
+	}
+	return closed
+}
+
+// --------------------------
+// Metrics
+// --------------------------
+
+// GameCounts is how many games a repository holds in memory
+type GameCounts struct {
+	Ongoing  int `json:"ongoing"`
+	Finished int `json:"finished"`
+}
+
+func CountGames(repo GameRepository) GameCounts {
+	var counts GameCounts
+	for _, g := range repo.Loaded() {
+		if state, _ := g.Outcome(); state == game.GameOngoing {
+			counts.Ongoing++
+		} else {
+			counts.Finished++
+		}
+	}
+	return counts
+}
+
+// PublishGameCounts serves the repository's live game counts as the
+// "games_loaded" expvar. Call it once.
+func PublishGameCounts(repo GameRepository) {
+	expvar.Publish("games_loaded", expvar.Func(func() any {
+		return CountGames(repo)
+	}))
+}
@@ -2,8 +2,10 @@ package store
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/lordsonvimal/synergy/apps/chess/ctxkeys"
 )
 
@@ -24,3 +26,72 @@ func GetRepoFromContext(ctx context.Context) (GameRepository, bool) {
 	repo, ok := ctx.Value(ctxkeys.GameRepoKey).(GameRepository)
 	return repo, ok
 }
+
+func AnalysisContext(repo AnalysisRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.WithValue(
+			c.Request.Context(),
+			ctxkeys.AnalysisRepoKey,
+			repo,
+		)
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+func GetAnalysisRepoFromContext(ctx context.Context) (AnalysisRepository, bool) {
+	repo, ok := ctx.Value(ctxkeys.AnalysisRepoKey).(AnalysisRepository)
+	return repo, ok
+}
+
+func ReviewContext(repo ReviewRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.WithValue(
+			c.Request.Context(),
+			ctxkeys.ReviewRepoKey,
+			repo,
+		)
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+func GetReviewRepoFromContext(ctx context.Context) (ReviewRepository, bool) {
+	repo, ok := ctx.Value(ctxkeys.ReviewRepoKey).(ReviewRepository)
+	return repo, ok
+}
+
+// playerCookie holds a visitor's anonymous player ID for a year
+const (
+	playerCookie       = "player_id"
+	playerCookieMaxAge = 365 * 24 * 60 * 60
+)
+
+// PlayerContext gives every visitor an anonymous player ID, kept in a
+// cookie, so the games they play can be listed for them later
+func PlayerContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := c.Cookie(playerCookie)
+		if err != nil || uuid.Validate(id) != nil {
+			id = uuid.New().String()
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(playerCookie, id, playerCookieMaxAge, "/", "", false, true)
+		}
+
+		ctx := context.WithValue(
+			c.Request.Context(),
+			ctxkeys.PlayerIDKey,
+			id,
+		)
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+func GetPlayerIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxkeys.PlayerIDKey).(string)
+	return id, ok
+}
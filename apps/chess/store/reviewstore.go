@@ -0,0 +1,54 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/lordsonvimal/synergy/apps/chess/game"
+	"github.com/lordsonvimal/synergy/apps/chess/review"
+)
+
+type ReviewRepository interface {
+	// GetOrStart returns the game's review job, starting one if needed
+	GetOrStart(*game.Game) *review.Job
+	Get(gameID string) (*review.Job, bool)
+	Delete(gameID string)
+}
+
+type ReviewStore struct {
+	mu   sync.Mutex
+	jobs map[string]*review.Job
+}
+
+func NewReviewStore() *ReviewStore {
+	return &ReviewStore{
+		jobs: make(map[string]*review.Job),
+	}
+}
+
+func (s *ReviewStore) GetOrStart(g *game.Game) *review.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j, ok := s.jobs[g.ID]; ok {
+		return j
+	}
+	j := review.Start(g.ID, g.StartFEN, g.Mode.Rules(), g.WAL.LoadFromMemory())
+	s.jobs[g.ID] = j
+	return j
+}
+
+func (s *ReviewStore) Get(gameID string) (*review.Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[gameID]
+	return j, ok
+}
+
+// Delete removes the job and cancels it if still running
+func (s *ReviewStore) Delete(gameID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j, ok := s.jobs[gameID]; ok {
+		j.Cancel()
+		delete(s.jobs, gameID)
+	}
+}
@@ -0,0 +1,321 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+	"github.com/lordsonvimal/synergy/apps/chess/game"
+	"github.com/lordsonvimal/synergy/apps/chess/logger"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// --------------------------
+// Schema
+// --------------------------
+
+// sqliteSchema holds a row per game and its WAL events. Game states and
+// colors are stored as their integer values, so new states must only ever
+// be appended.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS games (
+	id         TEXT PRIMARY KEY,
+	player_id  TEXT NOT NULL,
+	mode       TEXT NOT NULL,
+	start_fen  TEXT NOT NULL,
+	white_bot  INTEGER NOT NULL,
+	black_bot  INTEGER NOT NULL,
+	started_ns INTEGER NOT NULL,
+	ended_ns   INTEGER,
+	state      INTEGER NOT NULL,
+	winner     INTEGER NOT NULL,
+	white_rem  INTEGER NOT NULL,
+	black_rem  INTEGER NOT NULL,
+	moves      INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS games_by_player ON games (player_id, started_ns DESC);
+
+CREATE TABLE IF NOT EXISTS game_events (
+	game_id     TEXT NOT NULL REFERENCES games (id) ON DELETE CASCADE,
+	seq         INTEGER NOT NULL,
+	type        TEXT NOT NULL,
+	move_uci    TEXT NOT NULL,
+	server_ns   INTEGER NOT NULL,
+	lag_comp_ns INTEGER NOT NULL,
+	w_rem       INTEGER NOT NULL,
+	b_rem       INTEGER NOT NULL,
+	PRIMARY KEY (game_id, seq)
+) WITHOUT ROWID;
+`
+
+// --------------------------
+// SQLite game store
+// --------------------------
+
+// SQLiteGameStore keeps every game in a SQLite database. Games added or
+// loaded since startup stay cached in memory, and games in play are saved
+// after each of their events.
+type SQLiteGameStore struct {
+	db    *sql.DB
+	mu    sync.RWMutex
+	games map[string]*game.Game
+}
+
+// NewSQLiteGameStore opens, creating if needed, the database at path
+func NewSQLiteGameStore(path string) (*SQLiteGameStore, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite serialises writers anyway; one connection avoids busy errors
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteGameStore{
+		db:    db,
+		games: make(map[string]*game.Game),
+	}, nil
+}
+
+func (s *SQLiteGameStore) Close() error {
+	return s.db.Close()
+}
+
+// Add caches the game and saves it, then keeps saving it until it ends
+func (s *SQLiteGameStore) Add(g *game.Game) {
+	s.mu.Lock()
+	s.games[g.ID] = g
+	s.mu.Unlock()
+
+	// Subscribe before the first save so no event can slip in between
+	events, cancel := g.Subscribe()
+	if err := s.save(g); err != nil {
+		logger.Error(context.Background()).Err(err).Str("game", g.ID).Msg("Failed to save game")
+	}
+	if state, _ := g.Outcome(); state != game.GameOngoing {
+		cancel()
+		return
+	}
+	go s.track(g, events, cancel)
+}
+
+// track saves the game after each event until it ends. Events dropped for
+// falling behind are harmless: every save writes whatever is new in the WAL.
+func (s *SQLiteGameStore) track(g *game.Game, events <-chan game.Event, cancel func()) {
+	defer cancel()
+	for range events {
+		if err := s.save(g); err != nil {
+			logger.Error(context.Background()).Err(err).Str("game", g.ID).Msg("Failed to save game")
+		}
+		if state, _ := g.Outcome(); state != game.GameOngoing {
+			return
+		}
+	}
+}
+
+func (s *SQLiteGameStore) Get(id string) (*game.Game, bool) {
+	s.mu.RLock()
+	g, ok := s.games[id]
+	s.mu.RUnlock()
+	if ok {
+		return g, true
+	}
+
+	g, err := s.load(id)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.Error(context.Background()).Err(err).Str("game", id).Msg("Failed to load game")
+		}
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Another request may have loaded it meanwhile
+	if cached, ok := s.games[id]; ok {
+		return cached, true
+	}
+	s.games[id] = g
+	return g, true
+}
+
+// Delete removes the game from the cache and the database
+func (s *SQLiteGameStore) Delete(id string) {
+	s.mu.Lock()
+	delete(s.games, id)
+	s.mu.Unlock()
+
+	if _, err := s.db.Exec(`DELETE FROM games WHERE id = ?`, id); err != nil {
+		logger.Error(context.Background()).Err(err).Str("game", id).Msg("Failed to delete game")
+	}
+}
+
+func (s *SQLiteGameStore) Loaded() []*game.Game {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	games := make([]*game.Game, 0, len(s.games))
+	for _, g := range s.games {
+		games = append(games, g)
+	}
+	return games
+}
+
+// Evict drops the game from the cache; Get loads it again from the database
+func (s *SQLiteGameStore) Evict(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.games, id)
+}
+
+func (s *SQLiteGameStore) ListByPlayer(playerID string, offset, limit int) ([]GameSummary, error) {
+	rows, err := s.db.Query(`
+		SELECT id, mode, started_ns, state, winner, white_bot, black_bot, moves
+		FROM games WHERE player_id = ?
+		ORDER BY started_ns DESC LIMIT ? OFFSET ?`,
+		playerID, max(limit, 0), max(offset, 0))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []GameSummary
+	for rows.Next() {
+		var sum GameSummary
+		var startedNs int64
+		var winner, whiteBot, blackBot int
+		if err := rows.Scan(&sum.ID, &sum.Mode, &startedNs, &sum.State, &winner, &whiteBot, &blackBot, &sum.Moves); err != nil {
+			return nil, err
+		}
+		sum.StartedAt = time.Unix(0, startedNs)
+		sum.Winner = engine.Color(winner)
+		sum.BotColor = engine.NoColor
+		switch {
+		case whiteBot != 0:
+			sum.BotColor, sum.BotLevel = engine.White, whiteBot
+		case blackBot != 0:
+			sum.BotColor, sum.BotLevel = engine.Black, blackBot
+		}
+		summaries = append(summaries, sum)
+	}
+	return summaries, rows.Err()
+}
+
+// --------------------------
+// Saving and loading
+// --------------------------
+
+// save upserts the game's row and appends the WAL events not saved yet
+func (s *SQLiteGameStore) save(g *game.Game) error {
+	header := g.WAL.Header()
+	events := g.WAL.LoadFromMemory()
+	sum := summarize(g)
+
+	wRem, bRem := g.Mode.TimeNs, g.Mode.TimeNs
+	if len(events) > 0 {
+		last := events[len(events)-1]
+		wRem, bRem = last.WRem, last.BRem
+	}
+	var endedNs *int64
+	if sum.State != game.GameOngoing {
+		now := time.Now().UnixNano()
+		endedNs = &now
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO games (id, player_id, mode, start_fen, white_bot, black_bot, started_ns, ended_ns,
+			state, winner, white_rem, black_rem, moves)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			ended_ns = COALESCE(games.ended_ns, excluded.ended_ns),
+			state = excluded.state, winner = excluded.winner,
+			white_rem = excluded.white_rem, black_rem = excluded.black_rem, moves = excluded.moves`,
+		g.ID, g.PlayerID, header.Mode, header.StartFEN, header.White.BotLevel, header.Black.BotLevel,
+		g.StartedAt.UnixNano(), endedNs, sum.State, int(sum.Winner), wRem, bRem, sum.Moves)
+	if err != nil {
+		return err
+	}
+
+	var saved uint64
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(seq), 0) FROM game_events WHERE game_id = ?`, g.ID).Scan(&saved); err != nil {
+		return err
+	}
+	for _, e := range events {
+		if e.Seq <= saved {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO game_events (game_id, seq, type, move_uci, server_ns, lag_comp_ns, w_rem, b_rem)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			g.ID, e.Seq, e.Type, e.MoveUCI, e.ServerNs, e.LagCompNs, e.WRem, e.BRem)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// load rebuilds a game from its row and events. The human sides are the
+// player who created it. Loaded games are read-only, so one saved while
+// still in progress, whose WAL did not survive, comes back abandoned and
+// is saved so.
+func (s *SQLiteGameStore) load(id string) (*game.Game, error) {
+	header := game.WALHeader{GameID: id}
+	var playerID string
+	var state game.GameState
+	err := s.db.QueryRow(`
+		SELECT player_id, mode, start_fen, white_bot, black_bot, started_ns, state
+		FROM games WHERE id = ?`, id).
+		Scan(&playerID, &header.Mode, &header.StartFEN, &header.White.BotLevel, &header.Black.BotLevel, &header.CreatedNs, &state)
+	if err != nil {
+		return nil, err
+	}
+	for c := engine.Color(0); c < engine.ColorNB; c++ {
+		if header.Player(c).BotLevel == 0 {
+			header.Player(c).ID = playerID
+		}
+	}
+
+	rows, err := s.db.Query(`
+		SELECT seq, type, move_uci, server_ns, lag_comp_ns, w_rem, b_rem
+		FROM game_events WHERE game_id = ? ORDER BY seq`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []game.WALEvent
+	for rows.Next() {
+		var e game.WALEvent
+		if err := rows.Scan(&e.Seq, &e.Type, &e.MoveUCI, &e.ServerNs, &e.LagCompNs, &e.WRem, &e.BRem); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	g, err := game.Restore(header, events)
+	if err != nil {
+		return nil, err
+	}
+	if state == game.GameOngoing {
+		if err := s.save(g); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
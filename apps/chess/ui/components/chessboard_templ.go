@@ -31,6 +31,7 @@ func RenderChessBoard(g *game.Game) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
+		b := g.BoardCopy()
 		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"relative h-full flex items-center justify-center\" id=\"chessboard\"><table class=\"border-separate border-spacing-0\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
@@ -41,7 +42,7 @@ func RenderChessBoard(g *game.Game) templ.Component {
 				return templ_7745c5c3_Err
 			}
 			for file := 0; file < 8; file++ {
-				templ_7745c5c3_Err = RenderChessSquare(g, rank, file).Render(ctx, templ_7745c5c3_Buffer)
+				templ_7745c5c3_Err = RenderBoardSquare(b, rank, file, "/game/"+g.ID+"/select/").Render(ctx, templ_7745c5c3_Buffer)
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
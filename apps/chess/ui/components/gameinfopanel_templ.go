@@ -8,9 +8,13 @@ package components
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
-import "fmt"
+import (
+	"fmt"
+	"github.com/lordsonvimal/synergy/apps/chess/game"
+	"github.com/lordsonvimal/synergy/apps/chess/ui/helpers"
+)
 
-func GameInfoPanel() templ.Component {
+func GameInfoPanel(g *game.Game) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -31,12 +35,48 @@ func GameInfoPanel() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<aside class=\"w-72 bg-white shadow-lg rounded-xl p-6 flex flex-col gap-6\"><h2 class=\"text-2xl font-bold text-gray-900 border-b pb-2 mb-4\">Game Info</h2><!-- Turn --><div class=\"flex items-center justify-between\"><span class=\"font-semibold text-gray-700\">Turn:</span> <span data-text=\"$sideToMove === 0 ? 'White' : 'Black'\" data-class=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<aside class=\"w-72 bg-white shadow-lg rounded-xl p-6 flex flex-col gap-6\"><h2 class=\"text-2xl font-bold text-gray-900 border-b pb-2 mb-4\">Game Info</h2><!-- Opponent -->")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var2 string
-		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSExpression(fmt.Sprintf(`
+		if g.Bot != nil {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<div class=\"flex items-center justify-between\"><span class=\"font-semibold text-gray-700\">Computer:</span> <span class=\"px-3 py-1 bg-gray-200 text-gray-800 rounded-full font-medium\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var2 string
+			templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(g.Bot.Level.Name)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `ui/components/gameinfopanel.templ`, Line: 17, Col: 23}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, " (")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var3 string
+			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(helpers.FormatColor(g.Bot.Color))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `ui/components/gameinfopanel.templ`, Line: 17, Col: 61}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, ")</span></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<!-- Turn --><div class=\"flex items-center justify-between\"><span class=\"font-semibold text-gray-700\">Turn:</span> <span data-text=\"$sideToMove === 0 ? 'White' : 'Black'\" data-class=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var4 string
+		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSExpression(fmt.Sprintf(`
           (() => {
             return {
               'bg-blue-600': $sideToMove === 0,
@@ -45,13 +85,47 @@ func GameInfoPanel() templ.Component {
           })()
 			    `)))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `ui/components/gameinfopanel.templ`, Line: 20, Col: 10}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `ui/components/gameinfopanel.templ`, Line: 33, Col: 10}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "\" class=\"px-3 py-1 rounded-full text-white font-medium\"></span></div><!-- Check --><div class=\"flex items-center justify-between\" data-show=\"$isCheck\" style=\"display: none\"><span class=\"font-semibold text-gray-700\">Check:</span> <span class=\"px-3 py-1 bg-red-600 text-white rounded-full font-semibold\">King in check!</span></div><!-- Game State --><div class=\"flex flex-col\"><span class=\"font-semibold text-gray-700 mb-1\">Game State:</span> <span data-text=\"$gameStateText\" class=\"px-3 py-1 bg-yellow-100 text-yellow-800 rounded-full font-medium\"></span></div><!-- Moves --><div class=\"flex flex-col\"><span class=\"font-semibold text-gray-700 mb-1\">Moves:</span>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = RenderMoveList(g).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</div><!-- Draw claim --><div data-show=\"$canClaimDraw\" style=\"display: none\"><button class=\"w-full px-3 py-2 bg-gray-800 text-white rounded-lg font-medium hover:bg-gray-700\" data-on:click=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var5 string
+		templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(templ.JSExpression("@post('/game/" + g.ID + "/claim-draw')"))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `ui/components/gameinfopanel.templ`, Line: 63, Col: 80}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "\">Claim draw</button></div><!-- Review --><div data-show=\"$gameState !== 0\" style=\"display: none\"><a href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var6 templ.SafeURL
+		templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinURLErrs(templ.SafeURL("/game/" + g.ID + "/review"))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `ui/components/gameinfopanel.templ`, Line: 71, Col: 53}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\" class=\"px-3 py-1 rounded-full text-white font-medium\"></span></div><!-- Check --><div class=\"flex items-center justify-between\" data-show=\"$isCheck\" style=\"display: none\"><span class=\"font-semibold text-gray-700\">Check:</span> <span class=\"px-3 py-1 bg-red-600 text-white rounded-full font-semibold\">King in check!</span></div><!-- Game State --><div class=\"flex flex-col\"><span class=\"font-semibold text-gray-700 mb-1\">Game State:</span> <span data-text=\"$gameStateText\" class=\"px-3 py-1 bg-yellow-100 text-yellow-800 rounded-full font-medium\"></span></div><!-- Winner --><div class=\"flex items-center justify-between\" data-show=\"$winner !== 255\" style=\"display: none\"><span class=\"font-semibold text-gray-700\">Winner:</span> <span data-text=\"$winner === 0 ? 'White' : 'Black'\" class=\"px-3 py-1 bg-green-600 text-white rounded-full font-bold\"></span></div></aside>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "\" class=\"block w-full px-3 py-2 bg-blue-600 text-white text-center rounded-lg font-medium hover:bg-blue-700\">Review game</a></div><!-- Winner --><div class=\"flex items-center justify-between\" data-show=\"$winner !== 255\" style=\"display: none\"><span class=\"font-semibold text-gray-700\">Winner:</span> <span data-text=\"$winner === 0 ? 'White' : 'Black'\" class=\"px-3 py-1 bg-green-600 text-white rounded-full font-bold\"></span></div></aside>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -35,7 +35,7 @@ func PromotionButton(g *game.Game, piece engine.Piece) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		color := g.Board.SideToMove
+		color := g.SideToMove()
 		onClick := templ.JSExpression(fmt.Sprintf(`$promotionPiece = %d; @post('/game/%s/select/' + $promotedSquare)`, piece, g.ID))
 		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<button class=\"w-12 h-12 text-3xl hover:bg-gray-200 rounded\" data-on:click=\"")
 		if templ_7745c5c3_Err != nil {
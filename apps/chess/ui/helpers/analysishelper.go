@@ -0,0 +1,39 @@
+package helpers
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lordsonvimal/synergy/apps/chess/analysis"
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+)
+
+// FormatEval renders an evaluation from white's view: +0.35, -1.20, #3, #-2
+func FormatEval(e analysis.Eval) string {
+	switch {
+	case e.Result != "":
+		return e.Result
+	case e.Depth == 0:
+		return "…"
+	case e.Mate != 0:
+		return fmt.Sprintf("#%d", e.Mate)
+	}
+	return fmt.Sprintf("%+.2f", float64(e.Score)/100)
+}
+
+// EvalBarPercent maps an evaluation to white's share of the eval bar.
+// The logistic curve keeps small advantages visible and large ones short of
+// a full bar; only mate fills it.
+func EvalBarPercent(e analysis.Eval) int {
+	switch {
+	case e.Mate > 0:
+		return 100
+	case e.Mate < 0:
+		return 0
+	case e.Score >= engine.MATE_SCORE:
+		return 100
+	case e.Score <= -engine.MATE_SCORE:
+		return 0
+	}
+	return int(math.Round(100 / (1 + math.Exp(-float64(e.Score)/400))))
+}
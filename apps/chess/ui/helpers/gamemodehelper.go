@@ -1,6 +1,11 @@
 package helpers
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+	"github.com/lordsonvimal/synergy/apps/chess/game"
+)
 
 func FormatTime(ns int64) string {
 	mins := ns / 1_000_000_000 / 60
@@ -11,3 +16,48 @@ func FormatInc(ns int64) string {
 	secs := ns / 1_000_000_000
 	return fmt.Sprintf("%ds", secs)
 }
+
+func FormatColor(c engine.Color) string {
+	if c == engine.White {
+		return "White"
+	}
+	return "Black"
+}
+
+// FormatGameState describes a game state for display
+func FormatGameState(state game.GameState) string {
+	switch state {
+	case game.GameOngoing:
+		return "Ongoing"
+	case game.GameCheckmate:
+		return "Checkmate"
+	case game.GameResigned:
+		return "Resigned"
+	case game.GameClockFlagged:
+		return "Clock flagged"
+	case game.GameDrawStalemate:
+		return "Stalemate"
+	case game.GameDrawFiftyMove:
+		return "Fifty-move rule"
+	case game.GameDrawAgreement:
+		return "Draw by agreement"
+	case game.GameDrawThreefoldRepetition:
+		return "Threefold repetition"
+	case game.GameDrawInsufficientMaterial:
+		return "Insufficient material"
+	case game.GameAbandoned:
+		return "Abandoned"
+	case game.GameDisconnected:
+		return "Disconnected"
+	case game.GameInvalid:
+		return "Invalid"
+	case game.GameKingOfTheHill:
+		return "King of the hill"
+	case game.GameThreeCheck:
+		return "Three checks"
+	case game.GameKingExploded:
+		return "King exploded"
+	default:
+		return "Unknown"
+	}
+}
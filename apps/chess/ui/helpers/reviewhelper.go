@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+	"github.com/lordsonvimal/synergy/apps/chess/review"
+)
+
+// ReviewClassStyle returns the badge colors for a move classification
+func ReviewClassStyle(c review.Class) string {
+	switch c {
+	case review.ClassBest:
+		return "bg-green-100 text-green-800"
+	case review.ClassGood:
+		return "bg-gray-100 text-gray-700"
+	case review.ClassInaccuracy:
+		return "bg-yellow-100 text-yellow-800"
+	case review.ClassMistake:
+		return "bg-orange-100 text-orange-800"
+	case review.ClassBlunder:
+		return "bg-red-100 text-red-800"
+	}
+	return ""
+}
+
+// FormatReviewEval renders the evaluation after a move from white's view
+func FormatReviewEval(m review.MoveReview) string {
+	switch {
+	case m.Eval >= engine.MATE_SCORE:
+		return "1-0"
+	case m.Eval <= -engine.MATE_SCORE:
+		return "0-1"
+	case m.Mate != 0:
+		return fmt.Sprintf("#%d", m.Mate)
+	}
+	return fmt.Sprintf("%+.2f", float64(m.Eval)/100)
+}
+
+// FormatMoveNumber renders a 1-based ply as 12. or 12...
+func FormatMoveNumber(ply int) string {
+	if ply%2 == 1 {
+		return fmt.Sprintf("%d.", (ply+1)/2)
+	}
+	return fmt.Sprintf("%d...", ply/2)
+}
+
+// Percent returns done as a whole percentage of total (0 when total is 0)
+func Percent(done, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return done * 100 / total
+}
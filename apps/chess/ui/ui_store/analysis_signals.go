@@ -0,0 +1,49 @@
+package ui_store
+
+import (
+	"github.com/lordsonvimal/synergy/apps/chess/analysis"
+	"github.com/lordsonvimal/synergy/apps/chess/engine"
+)
+
+type AnalysisSignals struct {
+	SelectedSquare uint8  `json:"selectedSquare"`
+	PossibleMoves  []int  `json:"possibleMoves"`
+	FEN            string `json:"fen"`
+	FENError       string `json:"fenError"`
+	PromoteTo      string `json:"promoteTo,omitempty"` // q, r, b or n
+}
+
+func NewAnalysisSignals() *AnalysisSignals {
+	return &AnalysisSignals{
+		SelectedSquare: engine.NoSquare,
+		PossibleMoves:  []int{},
+		PromoteTo:      "q",
+	}
+}
+
+func (s *AnalysisSignals) UpdateFromSnapshot(snap analysis.Snapshot) {
+	s.FEN = snap.FEN
+	if snap.Selection != nil {
+		s.SelectedSquare = snap.Selection.From
+		s.PossibleMoves = make([]int, len(snap.Selection.Targets))
+		for i, t := range snap.Selection.Targets {
+			s.PossibleMoves[i] = int(t)
+		}
+	} else {
+		s.SelectedSquare = engine.NoSquare
+		s.PossibleMoves = []int{}
+	}
+}
+
+// PromotionPiece returns the piece chosen for promotions, a queen by default
+func (s *AnalysisSignals) PromotionPiece() engine.Piece {
+	switch s.PromoteTo {
+	case "r":
+		return engine.Rook
+	case "b":
+		return engine.Bishop
+	case "n":
+		return engine.Knight
+	}
+	return engine.Queen
+}
@@ -3,6 +3,7 @@ package ui_store
 import (
 	"github.com/lordsonvimal/synergy/apps/chess/engine"
 	"github.com/lordsonvimal/synergy/apps/chess/game"
+	"github.com/lordsonvimal/synergy/apps/chess/ui/helpers"
 )
 
 type ChessBoardSignals struct {
@@ -15,7 +16,8 @@ type ChessBoardSignals struct {
 	GameState      game.GameState `json:"gameState"`
 	GameStateText  string         `json:"gameStateText"` // <- new
 	IsCheck        bool           `json:"isCheck"`
-	Winner         engine.Color   `json:"winner"` // nil if game ongoing / draw
+	CanClaimDraw   bool           `json:"canClaimDraw"` // threefold repetition / fifty-move rule
+	Winner         engine.Color   `json:"winner"`       // nil if game ongoing / draw
 }
 
 func NewChessBoardSignals() *ChessBoardSignals {
@@ -29,6 +31,7 @@ func NewChessBoardSignals() *ChessBoardSignals {
 		GameState:      game.GameOngoing,
 		GameStateText:  "Ongoing",
 		IsCheck:        false,
+		CanClaimDraw:   false,
 		Winner:         engine.NoColor,
 	}
 }
@@ -54,59 +57,31 @@ func (s *ChessBoardSignals) ClearPromotion() {
 	s.PromotionPiece = engine.NoPiece
 }
 
+// UpdateFromGame copies the game's state into the signals. It only reads
+// the game through its locked accessors, as moves, flags and the engine
+// change it concurrently.
 func (s *ChessBoardSignals) UpdateFromGame(g *game.Game) {
-	s.SideToMove = g.Board.SideToMove
+	s.SideToMove = g.SideToMove()
 
 	// Update game state
 	s.IsCheck = g.IsCheck()
-	s.GameState = g.State
-	if g.State != game.GameOngoing && g.Winner != engine.NoColor {
-		c := g.Winner
-		s.Winner = c
+	s.CanClaimDraw = g.CanClaimDraw()
+	state, winner := g.Outcome()
+	s.GameState = state
+	if state != game.GameOngoing && winner != engine.NoColor {
+		s.Winner = winner
 	} else {
 		s.Winner = engine.NoColor
 	}
 
 	// Set human-readable GameState text
-	switch g.State {
-	case game.GameOngoing:
-		s.GameStateText = "Ongoing"
-	case game.GameCheckmate:
-		s.GameStateText = "Checkmate"
-	case game.GameResigned:
-		s.GameStateText = "Resigned"
-	case game.GameClockFlagged:
-		s.GameStateText = "Clock flagged"
-	case game.GameDrawStalemate:
-		s.GameStateText = "Stalemate"
-	case game.GameDrawFiftyMove:
-		s.GameStateText = "Fifty-move rule"
-	case game.GameDrawAgreement:
-		s.GameStateText = "Draw by agreement"
-	case game.GameDrawThreefoldRepetition:
-		s.GameStateText = "Threefold repetition"
-	case game.GameDrawInsufficientMaterial:
-		s.GameStateText = "Insufficient material"
-	case game.GameAbandoned:
-		s.GameStateText = "Abandoned"
-	case game.GameDisconnected:
-		s.GameStateText = "Disconnected"
-	case game.GameInvalid:
-		s.GameStateText = "Invalid"
-	default:
-		s.GameStateText = "Unknown"
-	}
+	s.GameStateText = helpers.FormatGameState(state)
 
-	// Update selection
 	// Update selection and possible moves
-	if g.Selection != nil {
-		s.SelectedSquare = g.Selection.FromSquare
-		s.PossibleMoves = make([]int, len(g.Selection.Targets))
-		for i, t := range g.Selection.Targets {
-			s.PossibleMoves[i] = int(t)
-		}
-	} else {
-		s.SelectedSquare = engine.NoSquare
-		s.PossibleMoves = []int{}
+	sel := g.SelectionSnapshot()
+	s.SelectedSquare = engine.NoSquare
+	if sel.FromSquare < int(engine.NoSquare) {
+		s.SelectedSquare = uint8(sel.FromSquare)
 	}
+	s.PossibleMoves = sel.Targets
 }